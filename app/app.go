@@ -9,7 +9,11 @@ import (
 	"github.com/jackvonhouse/enrichment/app/transport"
 	"github.com/jackvonhouse/enrichment/app/usecase"
 	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
 	"github.com/jackvonhouse/enrichment/internal/infrastructure/server/http"
+	kafkaUser "github.com/jackvonhouse/enrichment/internal/transport/kafka/user"
+	"github.com/jackvonhouse/enrichment/internal/worker/cache"
+	"github.com/jackvonhouse/enrichment/internal/worker/enrichment"
 	"github.com/jackvonhouse/enrichment/pkg/log"
 )
 
@@ -23,6 +27,13 @@ type App struct {
 	config config.Config
 	logger log.Logger
 	server http.Server
+	worker *enrichment.Pool
+
+	// nil, если приём сообщений из kafka отключён
+	consumer *kafkaUser.Transport
+
+	// nil, если кеш хранится не в postgres или очистка отключена
+	cleaner *cache.Cleaner
 }
 
 func New(
@@ -37,11 +48,29 @@ func New(
 	}
 
 	r := repository.New(i, logger)
-	s := service.New(r, logger)
-	u := usecase.New(s, logger)
-	t := transport.New(u, logger)
+
+	s, err := service.New(config, r, logger)
+	if err != nil {
+		return App{}, err
+	}
+
+	u := usecase.New(config, s, logger)
+	t := transport.New(config, u, logger)
 
 	httpServer := http.New(t.Router(), config.Server)
+	worker := enrichment.New(u.User, config.Enrichment.Worker, logger)
+
+	var consumer *kafkaUser.Transport
+
+	if i.Broker != nil {
+		consumer = kafkaUser.New(i.Broker, u.User, config.Kafka, logger)
+	}
+
+	var cleaner *cache.Cleaner
+
+	if config.Enrichment.Cache.Type == "postgres" && config.Enrichment.Cache.CleanupInterval > 0 {
+		cleaner = cache.New(r.EnrichmentCache, config.Enrichment.Cache.CleanupInterval, logger)
+	}
 
 	return App{
 		infrastructure: i,
@@ -52,15 +81,45 @@ func New(
 		config:         config,
 		logger:         logger,
 		server:         httpServer,
+		worker:         worker,
+		consumer:       consumer,
+		cleaner:        cleaner,
 	}, nil
 }
 
 func (a App) Run() error {
+	a.logger.Info("running enrichment workers...")
+
+	a.worker.Run()
+
+	if a.cleaner != nil {
+		a.logger.Info("running cache cleaner...")
+
+		a.cleaner.Run()
+	}
+
+	if a.consumer != nil {
+		a.logger.Info("running kafka consumer...")
+
+		a.consumer.Run()
+	}
+
 	a.logger.Info("running http server...")
 
 	return a.server.Run()
 }
 
+// Reenrich выполняет повторное обогащение без запуска сервера
+// и фоновых обработчиков
+func (a App) Reenrich(
+	ctx context.Context,
+	data dto.ReenrichDTO,
+	progress func(dto.ReenrichReport),
+) (dto.ReenrichReport, error) {
+
+	return a.useCase.User.Reenrich(ctx, data, progress)
+}
+
 func (a App) Shutdown(
 	ctx context.Context,
 ) error {
@@ -71,6 +130,34 @@ func (a App) Shutdown(
 		return err
 	}
 
+	if a.consumer != nil {
+		a.logger.Info("kafka consumer shutdowning..")
+
+		if err := a.consumer.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+
+	a.logger.Info("re-enrichment shutdowning..")
+
+	if err := a.useCase.Admin.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	a.logger.Info("enrichment workers shutdowning..")
+
+	if err := a.worker.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	if a.cleaner != nil {
+		a.logger.Info("cache cleaner shutdowning..")
+
+		if err := a.cleaner.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+
 	a.logger.Info("repository shutdowning..")
 
 	if err := a.repository.Shutdown(ctx); err != nil {
@@ -3,12 +3,15 @@ package infrastructure
 import (
 	"context"
 	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/infrastructure/kafka"
 	"github.com/jackvonhouse/enrichment/internal/infrastructure/postgres"
+	"github.com/jackvonhouse/enrichment/pkg/broker"
 	"github.com/jackvonhouse/enrichment/pkg/log"
 )
 
 type Infrastructure struct {
 	Storage postgres.Database
+	Broker  broker.Broker
 }
 
 func New(
@@ -26,7 +29,14 @@ func New(
 		return Infrastructure{}, err
 	}
 
+	var b broker.Broker
+
+	if config.Kafka.Enabled {
+		b = kafka.New(config.Kafka, infrastructureLog)
+	}
+
 	return Infrastructure{
 		Storage: db,
+		Broker:  b,
 	}, nil
 }
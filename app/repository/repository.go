@@ -4,12 +4,14 @@ import (
 	"context"
 	"github.com/jackvonhouse/enrichment/app/infrastructure"
 	"github.com/jackvonhouse/enrichment/internal/infrastructure/postgres"
+	"github.com/jackvonhouse/enrichment/internal/repository/enrichment"
 	"github.com/jackvonhouse/enrichment/internal/repository/user"
 	"github.com/jackvonhouse/enrichment/pkg/log"
 )
 
 type Repository struct {
-	User user.Repository
+	User            user.Repository
+	EnrichmentCache enrichment.Cache
 
 	Storage postgres.Database
 }
@@ -26,6 +28,10 @@ func New(
 			infrastructure.Storage.Database(),
 			repositoryLogger,
 		),
+		EnrichmentCache: enrichment.NewCache(
+			infrastructure.Storage.Database(),
+			repositoryLogger,
+		),
 		Storage: infrastructure.Storage,
 	}
 }
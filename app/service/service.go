@@ -1,7 +1,10 @@
 package service
 
 import (
+	"fmt"
 	"github.com/jackvonhouse/enrichment/app/repository"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/errors"
 	"github.com/jackvonhouse/enrichment/internal/service/enrichment"
 	"github.com/jackvonhouse/enrichment/internal/service/user"
 	"github.com/jackvonhouse/enrichment/pkg/log"
@@ -13,14 +16,38 @@ type Service struct {
 }
 
 func New(
+	config config.Config,
 	repository repository.Repository,
 	logger log.Logger,
-) Service {
+) (Service, error) {
 
 	serviceLogger := logger.WithField("layer", "service")
 
+	var cache enrichment.Cache
+
+	switch config.Enrichment.Cache.Type {
+	case "memory":
+		cache = enrichment.NewMemoryCache(config.Enrichment.Cache.Size)
+	case "postgres":
+		cache = repository.EnrichmentCache
+	case "none", "":
+	default:
+		serviceLogger.Warnf("unknown enrichment cache type: %s", config.Enrichment.Cache.Type)
+
+		return Service{}, errors.
+			ErrInvalidValue.
+			New(fmt.Sprintf("unknown enrichment cache type %q", config.Enrichment.Cache.Type))
+	}
+
+	e, err := enrichment.New(config.Enrichment, cache, serviceLogger)
+	if err != nil {
+		serviceLogger.Warn(err)
+
+		return Service{}, err
+	}
+
 	return Service{
-		Enrichment: enrichment.New(serviceLogger),
+		Enrichment: e,
 		User:       user.New(serviceLogger, repository.User),
-	}
+	}, nil
 }
@@ -4,8 +4,11 @@ import (
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/gorilla/mux"
 	"github.com/jackvonhouse/enrichment/app/usecase"
+	"github.com/jackvonhouse/enrichment/config"
 	"github.com/jackvonhouse/enrichment/internal/transport/graphql"
 	graphqlUser "github.com/jackvonhouse/enrichment/internal/transport/graphql/user"
+	httpAdmin "github.com/jackvonhouse/enrichment/internal/transport/http/admin"
+	httpHealth "github.com/jackvonhouse/enrichment/internal/transport/http/health"
 	httpUser "github.com/jackvonhouse/enrichment/internal/transport/http/user"
 	"github.com/jackvonhouse/enrichment/internal/transport/router"
 	"github.com/jackvonhouse/enrichment/pkg/log"
@@ -16,16 +19,21 @@ type Transport struct {
 }
 
 func New(
+	config config.Config,
 	useCase usecase.UseCase,
 	logger log.Logger,
 ) Transport {
 
 	transportLogger := logger.WithField("layer", "transport")
 
+	adminToken := config.Server.AdminToken.Reveal()
+
 	r := router.New("/api/v1")
 
 	r.Handle(map[string]router.Handlify{
-		"/user": httpUser.New(useCase.User, transportLogger),
+		"/user":   httpUser.New(useCase.User, transportLogger),
+		"/health": httpHealth.New(useCase.Health, transportLogger),
+		"/admin":  httpAdmin.New(useCase.Admin, adminToken, transportLogger),
 	})
 
 	h := graphqlUser.New(useCase.User, transportLogger)
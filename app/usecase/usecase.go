@@ -2,25 +2,43 @@ package usecase
 
 import (
 	"github.com/jackvonhouse/enrichment/app/service"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/usecase/admin"
+	"github.com/jackvonhouse/enrichment/internal/usecase/health"
 	"github.com/jackvonhouse/enrichment/internal/usecase/user"
 	"github.com/jackvonhouse/enrichment/pkg/log"
 )
 
 type UseCase struct {
-	User user.UseCase
+	User   user.UseCase
+	Health health.UseCase
+	Admin  admin.UseCase
 }
 
 func New(
+	config config.Config,
 	service service.Service,
 	logger log.Logger,
 ) UseCase {
 
 	useCaseLogger := logger.WithField("layer", "usecase")
 
+	userUseCase := user.New(
+		service.Enrichment,
+		service.User,
+		config.Enrichment.Worker,
+		useCaseLogger,
+	)
+
 	return UseCase{
-		User: user.New(
+		User: userUseCase,
+		Health: health.New(
+			service.Enrichment,
+			useCaseLogger,
+		),
+		Admin: admin.New(
 			service.Enrichment,
-			service.User,
+			userUseCase,
 			useCaseLogger,
 		),
 	}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"flag"
+	"os"
 
 	"github.com/jackvonhouse/enrichment/app"
 	"github.com/jackvonhouse/enrichment/config"
@@ -34,6 +35,15 @@ func main() {
 		return
 	}
 
+	if flag.Arg(0) == "reenrich" {
+		if err := reenrich(ctx, config, logger, flag.Args()[1:]); err != nil {
+			logger.Error(err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	app, err := app.New(ctx, config, logger)
 	if err != nil {
 		logger.Error(err)
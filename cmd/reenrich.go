@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/jackvonhouse/enrichment/app"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+// reenrich выполняет повторное обогащение пользователей и выводит
+// итоговый отчёт в stdout:
+//
+//	enrichment -config config/config.toml reenrich -gender male -batch-size 50
+func reenrich(
+	ctx context.Context,
+	config config.Config,
+	logger log.Logger,
+	args []string,
+) error {
+
+	var (
+		filter  dto.FilterDTO
+		data    dto.ReenrichDTO
+		genders string
+		country string
+	)
+
+	flags := flag.NewFlagSet("reenrich", flag.ExitOnError)
+
+	flags.StringVar(&filter.Name, "name", "", "Filter by name")
+	flags.StringVar(&filter.Surname, "surname", "", "Filter by surname")
+	flags.StringVar(&filter.Patronymic, "patronymic", "", "Filter by patronymic")
+	flags.IntVar(&filter.Age, "age", 0, "Filter by age")
+	flags.StringVar(&filter.AgeSort, "age-sort-operator", "", "Age comparison: eq, ne, gt, ge, lt, le")
+	flags.StringVar(&genders, "gender", "", "Comma-separated genders")
+	flags.StringVar(&country, "country", "", "Comma-separated country codes")
+	flags.IntVar(&data.BatchSize, "batch-size", 100, "Users per batch")
+	flags.BoolVar(&data.DryRun, "dry-run", false, "Report changes without saving them")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	filter.Gender = splitList(genders)
+	filter.Country = splitList(country)
+	data.Filter = filter
+
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	a, err := app.New(ctx, config, logger)
+	if err != nil {
+		return err
+	}
+
+	defer a.Shutdown(context.Background())
+
+	report, err := a.Reenrich(ctx, data, nil)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(report); err != nil {
+		logger.Warnf("can't print report: %s", err)
+	}
+
+	return err
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	items := strings.Split(value, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+
+	return items
+}
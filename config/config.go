@@ -1,11 +1,14 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/jackvonhouse/enrichment/pkg/log"
 	"github.com/spf13/viper"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type Database struct {
@@ -27,13 +30,142 @@ func (d Database) String() string {
 	)
 }
 
+// ServerHTTP.AdminToken - токен доступа к /admin,
+// пустой токен отключает эти эндпоинты
 type ServerHTTP struct {
-	Port int
+	Port       int
+	AdminToken Secret
+}
+
+type EnrichmentRetry struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+type EnrichmentBreaker struct {
+	FailureThreshold int
+	CoolDown         time.Duration
+	HalfOpenRequests int
+}
+
+// EnrichmentRateLimit - token bucket для исходящих запросов к провайдеру;
+// Rate - запросов в секунду, 0 отключает ограничение
+type EnrichmentRateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+type EnrichmentPolicy struct {
+	MinProbability float64
+	MinCount       int
+	OnUnknown      string
+	Default        string
+}
+
+type EnrichmentOffline struct {
+	Dataset        string
+	ReloadInterval time.Duration
+}
+
+// Secret скрывает значение при выводе в лог и сериализации,
+// само значение доступно только через Reveal
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return "******"
+}
+
+func (s Secret) GoString() string { return s.String() }
+
+func (s Secret) MarshalJSON() ([]byte, error) { return json.Marshal(s.String()) }
+
+func (s Secret) Reveal() string { return string(s) }
+
+type EnrichmentProvider struct {
+	Provider  string
+	Chain     []string
+	Links     map[string]EnrichmentProvider
+	URL       string
+	Value     string
+	Timeout   time.Duration
+	Retry     EnrichmentRetry
+	Breaker   EnrichmentBreaker
+	RateLimit EnrichmentRateLimit
+	Policy    EnrichmentPolicy
+	Offline   EnrichmentOffline
+	APIKey    Secret
+}
+
+// Link - настройки звена цепочки name: раздел providers.<name>
+// атрибута, незаданные в нём ключи берутся из самого атрибута
+func (p EnrichmentProvider) Link(name string) EnrichmentProvider {
+	if link, ok := p.Links[name]; ok {
+		return link
+	}
+
+	return p
+}
+
+type EnrichmentHTTP struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+}
+
+type EnrichmentCache struct {
+	Type        string
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+
+	// CleanupInterval - период удаления истёкших записей из postgres,
+	// 0 отключает удаление
+	CleanupInterval time.Duration
+}
+
+type EnrichmentWorker struct {
+	Workers      int
+	BatchSize    int
+	PollInterval time.Duration
+	Lease        time.Duration
+	MaxAttempts  int
+	RetryDelay   time.Duration
+}
+
+type EnrichmentNormalize struct {
+	Transliteration string
+}
+
+type Enrichment struct {
+	Age       EnrichmentProvider
+	Gender    EnrichmentProvider
+	Country   EnrichmentProvider
+	HTTP      EnrichmentHTTP
+	Cache     EnrichmentCache
+	Worker    EnrichmentWorker
+	Normalize EnrichmentNormalize
+}
+
+type Kafka struct {
+	Enabled     bool
+	Brokers     []string
+	GroupID     string
+	Topic       string
+	FailedTopic string
+	Timeout     time.Duration
 }
 
 type Config struct {
-	Database Database
-	Server   ServerHTTP
+	Database   Database
+	Server     ServerHTTP
+	Enrichment Enrichment
+	Kafka      Kafka
 }
 
 func New(
@@ -46,6 +178,8 @@ func New(
 	viper.SetConfigType(configType)
 	viper.SetConfigFile(configPath)
 
+	setDefaults()
+
 	if err := viper.ReadInConfig(); err != nil {
 		logger.WithFields(map[string]any{
 			"layer":       "config",
@@ -55,6 +189,37 @@ func New(
 		return Config{}, fmt.Errorf("error on reading config: %s", err)
 	}
 
+	providers := map[string]*EnrichmentProvider{
+		"enrichment.age":     {},
+		"enrichment.gender":  {},
+		"enrichment.country": {},
+	}
+
+	for prefix, provider := range providers {
+		p, err := newEnrichmentProvider(prefix)
+		if err != nil {
+			logger.WithFields(map[string]any{
+				"layer":    "config",
+				"provider": prefix,
+			}).Warnf("error on reading provider config: %s", err)
+
+			return Config{}, err
+		}
+
+		*provider = p
+	}
+
+	adminToken, err := readSecret(
+		viper.GetString("server.http.admin.token_env"),
+		viper.GetString("server.http.admin.token_file"),
+	)
+	if err != nil {
+		logger.WithField("layer", "config").
+			Warnf("error on reading admin token: %s", err)
+
+		return Config{}, err
+	}
+
 	postgresPrefix := "database.postgres"
 
 	return Config{
@@ -85,7 +250,341 @@ func New(
 		},
 
 		Server: ServerHTTP{
-			Port: viper.GetInt("server.http.port"),
+			Port:       viper.GetInt("server.http.port"),
+			AdminToken: adminToken,
+		},
+
+		Enrichment: Enrichment{
+			Age:     *providers["enrichment.age"],
+			Gender:  *providers["enrichment.gender"],
+			Country: *providers["enrichment.country"],
+
+			HTTP: EnrichmentHTTP{
+				MaxIdleConns: viper.GetInt(
+					"enrichment.http.max_idle_conns",
+				),
+
+				MaxIdleConnsPerHost: viper.GetInt(
+					"enrichment.http.max_idle_conns_per_host",
+				),
+
+				MaxConnsPerHost: viper.GetInt(
+					"enrichment.http.max_conns_per_host",
+				),
+
+				IdleConnTimeout: viper.GetDuration(
+					"enrichment.http.idle_conn_timeout",
+				),
+			},
+
+			Cache: EnrichmentCache{
+				Type: viper.GetString(
+					"enrichment.cache.type",
+				),
+
+				Size: viper.GetInt(
+					"enrichment.cache.size",
+				),
+
+				TTL: viper.GetDuration(
+					"enrichment.cache.ttl",
+				),
+
+				NegativeTTL: viper.GetDuration(
+					"enrichment.cache.negative_ttl",
+				),
+
+				CleanupInterval: viper.GetDuration(
+					"enrichment.cache.cleanup_interval",
+				),
+			},
+
+			Worker: EnrichmentWorker{
+				Workers: viper.GetInt(
+					"enrichment.worker.workers",
+				),
+
+				BatchSize: viper.GetInt(
+					"enrichment.worker.batch_size",
+				),
+
+				PollInterval: viper.GetDuration(
+					"enrichment.worker.poll_interval",
+				),
+
+				Lease: viper.GetDuration(
+					"enrichment.worker.lease",
+				),
+
+				MaxAttempts: viper.GetInt(
+					"enrichment.worker.max_attempts",
+				),
+
+				RetryDelay: viper.GetDuration(
+					"enrichment.worker.retry_delay",
+				),
+			},
+
+			Normalize: EnrichmentNormalize{
+				Transliteration: viper.GetString(
+					"enrichment.normalize.transliteration",
+				),
+			},
+		},
+
+		Kafka: Kafka{
+			Enabled: viper.GetBool("kafka.enabled"),
+			Brokers: viper.GetStringSlice("kafka.brokers"),
+			GroupID: viper.GetString("kafka.group_id"),
+			Topic:   viper.GetString("kafka.topic"),
+
+			FailedTopic: viper.GetString("kafka.failed_topic"),
+			Timeout:     viper.GetDuration("kafka.timeout"),
+		},
+	}, nil
+}
+
+func setDefaults() {
+	viper.SetDefault("enrichment.age.provider", "agify")
+	viper.SetDefault("enrichment.age.url", "https://api.agify.io")
+
+	viper.SetDefault("enrichment.gender.provider", "genderize")
+	viper.SetDefault("enrichment.gender.url", "https://api.genderize.io")
+
+	viper.SetDefault("enrichment.country.provider", "nationalize")
+	viper.SetDefault("enrichment.country.url", "https://api.nationalize.io")
+
+	for _, attribute := range []string{"age", "gender", "country"} {
+		prefix := fmt.Sprintf("enrichment.%s", attribute)
+
+		viper.SetDefault(fmt.Sprintf("%s.timeout", prefix), "2s")
+
+		viper.SetDefault(fmt.Sprintf("%s.retry.max_attempts", prefix), 3)
+		viper.SetDefault(fmt.Sprintf("%s.retry.base_delay", prefix), "100ms")
+		viper.SetDefault(fmt.Sprintf("%s.retry.max_delay", prefix), "1s")
+
+		viper.SetDefault(fmt.Sprintf("%s.breaker.failure_threshold", prefix), 5)
+		viper.SetDefault(fmt.Sprintf("%s.breaker.cool_down", prefix), "30s")
+		viper.SetDefault(fmt.Sprintf("%s.breaker.half_open_requests", prefix), 1)
+
+		viper.SetDefault(fmt.Sprintf("%s.rate_limit.rate", prefix), 10)
+		viper.SetDefault(fmt.Sprintf("%s.rate_limit.burst", prefix), 10)
+
+		viper.SetDefault(fmt.Sprintf("%s.policy.on_unknown", prefix), "fail")
+
+		viper.SetDefault(fmt.Sprintf("%s.offline.reload_interval", prefix), "30s")
+	}
+
+	viper.SetDefault("enrichment.http.max_idle_conns", 100)
+	viper.SetDefault("enrichment.http.max_idle_conns_per_host", 10)
+	viper.SetDefault("enrichment.http.max_conns_per_host", 20)
+	viper.SetDefault("enrichment.http.idle_conn_timeout", "90s")
+
+	viper.SetDefault("enrichment.cache.type", "memory")
+	viper.SetDefault("enrichment.cache.size", 10000)
+	viper.SetDefault("enrichment.cache.ttl", "24h")
+	viper.SetDefault("enrichment.cache.negative_ttl", "1h")
+	viper.SetDefault("enrichment.cache.cleanup_interval", "10m")
+
+	viper.SetDefault("enrichment.worker.workers", 4)
+	viper.SetDefault("enrichment.worker.batch_size", 10)
+	viper.SetDefault("enrichment.worker.poll_interval", "1s")
+	viper.SetDefault("enrichment.worker.lease", "1m")
+	viper.SetDefault("enrichment.worker.max_attempts", 5)
+	viper.SetDefault("enrichment.worker.retry_delay", "30s")
+
+	viper.SetDefault("enrichment.normalize.transliteration", "icao")
+
+	viper.SetDefault("kafka.enabled", false)
+	viper.SetDefault("kafka.brokers", []string{"127.0.0.1:9092"})
+	viper.SetDefault("kafka.group_id", "enrichment")
+	viper.SetDefault("kafka.topic", "FIO")
+	viper.SetDefault("kafka.failed_topic", "FIO_FAILED")
+	viper.SetDefault("kafka.timeout", "5s")
+}
+
+func newEnrichmentProvider(
+	prefix string,
+) (EnrichmentProvider, error) {
+
+	attributeKey := func(key string) string {
+		return fmt.Sprintf("%s.%s", prefix, key)
+	}
+
+	provider, err := readEnrichmentProvider(attributeKey)
+	if err != nil {
+		return EnrichmentProvider{}, err
+	}
+
+	provider.Chain = viper.GetStringSlice(attributeKey("chain"))
+	provider.Links = make(map[string]EnrichmentProvider)
+
+	// Прежний offline.fallback - то же, что chain = [provider, "offline"]
+	if viper.GetBool(attributeKey("offline.fallback")) {
+		if len(provider.Chain) > 0 {
+			return EnrichmentProvider{}, fmt.Errorf(
+				"%s: offline.fallback can't be combined with chain, add \"offline\" to the chain instead",
+				prefix,
+			)
+		}
+
+		if provider.Provider != "offline" {
+			provider.Chain = []string{provider.Provider, "offline"}
+		}
+	}
+
+	names := provider.Chain
+	if len(names) == 0 {
+		names = []string{provider.Provider}
+	}
+
+	for _, name := range names {
+		linkPrefix := fmt.Sprintf("%s.providers.%s", prefix, name)
+
+		if !viper.IsSet(linkPrefix) {
+			continue
+		}
+
+		link, err := readEnrichmentProvider(func(key string) string {
+			if linkKey := fmt.Sprintf("%s.%s", linkPrefix, key); viper.IsSet(linkKey) {
+				return linkKey
+			}
+
+			return attributeKey(key)
+		})
+		if err != nil {
+			return EnrichmentProvider{}, err
+		}
+
+		link.Provider = name
+		provider.Links[name] = link
+	}
+
+	return provider, nil
+}
+
+// readEnrichmentProvider читает настройки провайдера, key
+// переводит имя настройки в ключ конфигурации
+func readEnrichmentProvider(
+	key func(string) string,
+) (EnrichmentProvider, error) {
+
+	apiKey, err := readSecret(
+		viper.GetString(key("api_key_env")),
+		viper.GetString(key("api_key_file")),
+	)
+	if err != nil {
+		return EnrichmentProvider{}, err
+	}
+
+	return EnrichmentProvider{
+		Provider: viper.GetString(
+			key("provider"),
+		),
+
+		URL: viper.GetString(
+			key("url"),
+		),
+
+		APIKey: apiKey,
+
+		Value: viper.GetString(
+			key("value"),
+		),
+
+		Timeout: viper.GetDuration(
+			key("timeout"),
+		),
+
+		Retry: EnrichmentRetry{
+			MaxAttempts: viper.GetInt(
+				key("retry.max_attempts"),
+			),
+
+			BaseDelay: viper.GetDuration(
+				key("retry.base_delay"),
+			),
+
+			MaxDelay: viper.GetDuration(
+				key("retry.max_delay"),
+			),
+		},
+
+		Breaker: EnrichmentBreaker{
+			FailureThreshold: viper.GetInt(
+				key("breaker.failure_threshold"),
+			),
+
+			CoolDown: viper.GetDuration(
+				key("breaker.cool_down"),
+			),
+
+			HalfOpenRequests: viper.GetInt(
+				key("breaker.half_open_requests"),
+			),
+		},
+
+		RateLimit: EnrichmentRateLimit{
+			Rate: viper.GetFloat64(
+				key("rate_limit.rate"),
+			),
+
+			Burst: viper.GetInt(
+				key("rate_limit.burst"),
+			),
+		},
+
+		Policy: EnrichmentPolicy{
+			MinProbability: viper.GetFloat64(
+				key("policy.min_probability"),
+			),
+
+			MinCount: viper.GetInt(
+				key("policy.min_count"),
+			),
+
+			OnUnknown: viper.GetString(
+				key("policy.on_unknown"),
+			),
+
+			Default: viper.GetString(
+				key("policy.default"),
+			),
+		},
+
+		Offline: EnrichmentOffline{
+			Dataset: viper.GetString(
+				key("offline.dataset"),
+			),
+
+			ReloadInterval: viper.GetDuration(
+				key("offline.reload_interval"),
+			),
 		},
 	}, nil
 }
+
+// readSecret читает секрет из переменной окружения envName,
+// а если она не задана - из файла path (например, секрета Docker)
+func readSecret(
+	envName string,
+	path string,
+) (Secret, error) {
+
+	if envName != "" {
+		if value, ok := os.LookupEnv(envName); ok {
+			return Secret(strings.TrimSpace(value)), nil
+		}
+	}
+
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("can't read secret file %s: %s", path, err)
+	}
+
+	return Secret(strings.TrimSpace(string(data))), nil
+}
@@ -0,0 +1,53 @@
+package dto
+
+import "time"
+
+// ProviderQuota содержит nil в Limit, Remaining и ResetAt,
+// пока провайдер не сообщил о квоте
+type ProviderQuota struct {
+	Attribute string     `json:"attribute"`
+	Provider  string     `json:"provider"`
+	Limit     *int       `json:"limit"`
+	Remaining *int       `json:"remaining"`
+	ResetAt   *time.Time `json:"reset_at"`
+	Exhausted bool       `json:"exhausted"`
+}
+
+const (
+	ReenrichRunning = "running"
+	ReenrichDone    = "done"
+	ReenrichFailed  = "failed"
+)
+
+type ReenrichDTO struct {
+	Filter    FilterDTO
+	BatchSize int
+
+	// DryRun только подсчитывает изменения, не сохраняя их
+	DryRun bool
+}
+
+// ReenrichDiff - число пользователей, у которых атрибут изменился,
+// остался прежним или пропущен, так как задан вручную
+type ReenrichDiff struct {
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+	Manual    int `json:"manual"`
+}
+
+type ReenrichReport struct {
+	Status     string     `json:"status"`
+	DryRun     bool       `json:"dry_run"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Error      string     `json:"error,omitempty"`
+
+	Processed int `json:"processed"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+
+	Age     ReenrichDiff `json:"age"`
+	Gender  ReenrichDiff `json:"gender"`
+	Country ReenrichDiff `json:"country"`
+}
@@ -1,19 +1,57 @@
 package dto
 
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	EnrichmentPending    = "pending"
+	EnrichmentProcessing = "processing"
+	EnrichmentDone       = "done"
+	EnrichmentFailed     = "failed"
+)
+
 type User struct {
-	ID         int    `json:"id"`
-	Name       string `json:"name"`
-	Surname    string `json:"surname"`
-	Patronymic string `json:"patronymic"`
-	Age        int    `json:"age"`
-	Gender     string `json:"gender"`
-	Country    string `json:"country"`
+	ID         int     `json:"id"`
+	Name       string  `json:"name"`
+	Surname    string  `json:"surname"`
+	Patronymic string  `json:"patronymic"`
+	Age        *int    `json:"age"`
+	Gender     *string `json:"gender"`
+	Country    *string `json:"country"`
+
+	AgeCount           *int      `json:"age_count" db:"age_count"`
+	GenderProbability  *float64  `json:"gender_probability" db:"gender_probability"`
+	GenderCount        *int      `json:"gender_count" db:"gender_count"`
+	CountryProbability *float64  `json:"country_probability" db:"country_probability"`
+	CountryCount       *int      `json:"country_count" db:"country_count"`
+	Countries          Countries `json:"countries" db:"countries"`
+
+	AgeSource     *string `json:"age_source" db:"age_source"`
+	GenderSource  *string `json:"gender_source" db:"gender_source"`
+	CountrySource *string `json:"country_source" db:"country_source"`
+
+	CountryHint *string `json:"country_hint" db:"country_hint"`
+
+	EnrichmentStatus   string  `json:"enrichment_status" db:"enrichment_status"`
+	EnrichmentError    *string `json:"enrichment_error,omitempty" db:"enrichment_error"`
+	EnrichmentAttempts int     `json:"-" db:"enrichment_attempts"`
+
+	// EnrichmentLease меняется при каждом захвате пользователя
+	// обработчиком; результат записывает только его владелец
+	EnrichmentLease int `json:"-" db:"enrichment_lease"`
 }
 
 type CreateDTO struct {
 	Name       string `json:"name"`
 	Surname    string `json:"surname"`
 	Patronymic string `json:"patronymic"`
+
+	// CountryHint - необязательный код страны (ISO 3166-1 alpha-2),
+	// уточняющий определение возраста и пола
+	CountryHint string `json:"country_hint"`
 }
 
 type UpdateDTO struct {
@@ -26,9 +64,46 @@ type UpdateDTO struct {
 	Country    string `json:"country"`
 }
 
+// Способы подсчёта общего числа строк выборки
+const (
+	CountNone     = "none"
+	CountExact    = "exact"
+	CountEstimate = "estimate"
+)
+
 type GetDTO struct {
 	Limit  int `json:"limit"`
 	Offset int `json:"offset"`
+
+	// Count - способ подсчёта Total, пустая строка - без подсчёта
+	Count string `json:"count"`
+}
+
+// UserList содержит nil в Total, если подсчёт не запрашивался;
+// Estimated означает, что Total - оценка планировщика PostgreSQL
+type UserList struct {
+	Items     []User `json:"items"`
+	Total     *int   `json:"total"`
+	Estimated bool   `json:"estimated"`
+	Limit     int    `json:"limit"`
+	Offset    int    `json:"offset"`
+}
+
+// PageDTO - выборка по курсору: Limit строк после Cursor,
+// а при Backward - перед ним; пустой Cursor - с начала (или с конца)
+type PageDTO struct {
+	Limit    int
+	Cursor   string
+	Backward bool
+}
+
+// UserPage содержит курсор каждой строки в Cursors; NextCursor и PrevCursor
+// равны nil, если в этом направлении строк больше нет
+type UserPage struct {
+	Items      []User   `json:"items"`
+	Cursors    []string `json:"-"`
+	NextCursor *string  `json:"next_cursor"`
+	PrevCursor *string  `json:"prev_cursor"`
 }
 
 type FilterDTO struct {
@@ -39,15 +114,131 @@ type FilterDTO struct {
 	AgeSort    string
 	Gender     []string
 	Country    []string
+
+	// Conditions дополняют поля выше и объединяются с ними через AND
+	Conditions []Condition
+
+	// AfterID оставляет пользователей с id больше заданного,
+	// 0 - без ограничения
+	AfterID int
 }
 
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpGt       = "gt"
+	OpGe       = "ge"
+	OpLt       = "lt"
+	OpLe       = "le"
+	OpIn       = "in"
+	OpNotIn    = "not_in"
+	OpBetween  = "between"
+	OpPrefix   = "prefix"
+	OpContains = "contains"
+	OpExact    = "exact"
+	OpIsNull   = "is_null"
+	OpSimilar  = "similar"
+)
+
+// Condition - условие на поле пользователя. Значения передаются
+// строками, репозиторий приводит их к типу столбца: between ждёт
+// два значения, in и not_in - одно и больше, is_null - "true" или
+// "false" (без значения - "true"), остальные - ровно одно
+type Condition struct {
+	Field    string
+	Operator string
+	Values   []string
+}
+
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+
+	NullsFirst = "first"
+	NullsLast  = "last"
+)
+
+// SortKey - столбец сортировки; пустой Order - по возрастанию,
+// пустой Nulls - как в PostgreSQL: NULL больше любого значения
+type SortKey struct {
+	Field string
+	Order string
+	Nulls string
+}
+
+// SortDTO - ключи сортировки по убыванию приоритета;
+// id всегда замыкает порядок
 type SortDTO struct {
-	SortBy    string
-	SortOrder string
+	Keys []SortKey
+}
+
+// Источники значений, полученных не от провайдеров обогащения
+const (
+	SourceDefault    = "default"
+	SourceManual     = "manual"
+	SourcePatronymic = "patronymic"
+)
+
+type AgeDTO struct {
+	Age    int    `json:"age"`
+	Count  int    `json:"count"`
+	Source string `json:"source"`
+}
+
+type GenderDTO struct {
+	Gender      string  `json:"gender"`
+	Probability float64 `json:"probability"`
+	Count       int     `json:"count"`
+	Source      string  `json:"source"`
+}
+
+type CountryProbability struct {
+	CountryID   string  `json:"country_id"`
+	Probability float64 `json:"probability"`
+}
+
+type Countries []CountryProbability
+
+func (c Countries) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+func (c *Countries) Scan(src any) error {
+	switch data := src.(type) {
+	case nil:
+		*c = nil
+
+		return nil
+	case []byte:
+		return json.Unmarshal(data, c)
+	case string:
+		return json.Unmarshal([]byte(data), c)
+	default:
+		return fmt.Errorf("can't scan countries from %T", src)
+	}
+}
+
+type CountryDTO struct {
+	Country     string    `json:"country"`
+	Probability float64   `json:"probability"`
+	Count       int       `json:"count"`
+	Countries   Countries `json:"countries"`
+	Source      string    `json:"source"`
 }
 
+// EnrichmentDTO содержит nil для атрибутов, оставленных
+// незаполненными политикой обогащения
 type EnrichmentDTO struct {
-	Age     int
-	Gender  string
-	Country string
+	Age     *AgeDTO
+	Gender  *GenderDTO
+	Country *CountryDTO
 }
@@ -0,0 +1,28 @@
+package dto
+
+type ProviderHealth struct {
+	Attribute string `json:"attribute"`
+	Provider  string `json:"provider"`
+	State     string `json:"state"`
+}
+
+type CacheStats struct {
+	Attribute string `json:"attribute"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+}
+
+// CoalescingStats - Calls запросов ушло к провайдеру,
+// ещё Coalesced дождались результата одного из них
+type CoalescingStats struct {
+	Attribute string `json:"attribute"`
+	Calls     uint64 `json:"calls"`
+	Coalesced uint64 `json:"coalesced"`
+}
+
+type Health struct {
+	Status     string            `json:"status"`
+	Providers  []ProviderHealth  `json:"providers"`
+	Cache      []CacheStats      `json:"cache"`
+	Coalescing []CoalescingStats `json:"coalescing"`
+}
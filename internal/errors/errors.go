@@ -1,6 +1,10 @@
 package errors
 
-import "github.com/jackvonhouse/enrichment/pkg/errors"
+import (
+	"fmt"
+	"github.com/jackvonhouse/enrichment/pkg/errors"
+	"time"
+)
 
 var (
 	ErrCantEnrichment = errors.NewType("can't user")
@@ -9,4 +13,19 @@ var (
 	ErrNotFound       = errors.NewType("not found")
 	ErrEmptyField     = errors.NewType("empty field")
 	ErrInvalidValue   = errors.NewType("invalid value")
+	ErrCircuitOpen    = errors.NewType("circuit open")
+	ErrUnknownName    = errors.NewType("unknown name")
+	ErrLowConfidence  = errors.NewType("low confidence")
+	ErrQuotaExhausted = errors.NewType("quota exhausted")
+	ErrRateLimited    = errors.NewType("rate limited")
+	ErrLeaseLost      = errors.NewType("lease lost")
 )
+
+// RetryAfter сообщает, через сколько запрос имеет смысл повторить
+type RetryAfter struct {
+	Delay time.Duration
+}
+
+func (r RetryAfter) Error() string {
+	return fmt.Sprintf("retry after %s", r.Delay)
+}
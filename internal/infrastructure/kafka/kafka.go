@@ -0,0 +1,114 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/pkg/broker"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"github.com/segmentio/kafka-go"
+)
+
+type Broker struct {
+	reader *kafka.Reader
+	writer *kafka.Writer
+}
+
+func New(
+	config config.Kafka,
+	logger log.Logger,
+) Broker {
+
+	logger.Infof(
+		"kafka brokers: %v, topic: %s, group: %s",
+		config.Brokers, config.Topic, config.GroupID,
+	)
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: config.Brokers,
+		GroupID: config.GroupID,
+		Topic:   config.Topic,
+	})
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(config.Brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+
+	return Broker{
+		reader: reader,
+		writer: writer,
+	}
+}
+
+func (b Broker) Fetch(
+	ctx context.Context,
+) (broker.Message, error) {
+
+	msg, err := b.reader.FetchMessage(ctx)
+	if err != nil {
+		return broker.Message{}, err
+	}
+
+	headers := make(map[string]string, len(msg.Headers))
+
+	for _, header := range msg.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+
+	return broker.Message{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Headers:   headers,
+	}, nil
+}
+
+func (b Broker) Commit(
+	ctx context.Context,
+	msg broker.Message,
+) error {
+
+	return b.reader.CommitMessages(ctx, kafka.Message{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+	})
+}
+
+func (b Broker) Publish(
+	ctx context.Context,
+	topic string,
+	msg broker.Message,
+) error {
+
+	headers := make([]kafka.Header, 0, len(msg.Headers))
+
+	for key, value := range msg.Headers {
+		headers = append(headers, kafka.Header{
+			Key:   key,
+			Value: []byte(value),
+		})
+	}
+
+	return b.writer.WriteMessages(ctx, kafka.Message{
+		Topic:   topic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	})
+}
+
+func (b Broker) Close() error {
+	readerErr := b.reader.Close()
+	writerErr := b.writer.Close()
+
+	if readerErr != nil {
+		return readerErr
+	}
+
+	return writerErr
+}
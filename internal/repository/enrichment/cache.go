@@ -0,0 +1,163 @@
+package enrichment
+
+import (
+	"context"
+	"database/sql"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type Cache struct {
+	db     *sqlx.DB
+	logger log.Logger
+}
+
+func NewCache(
+	db *sqlx.DB,
+	logger log.Logger,
+) Cache {
+
+	return Cache{
+		db:     db,
+		logger: logger.WithField("unit", "enrichment_cache"),
+	}
+}
+
+func (c Cache) Get(
+	ctx context.Context,
+	key string,
+) ([]byte, bool, error) {
+
+	query, args, err := sq.
+		Select("value").
+		From("enrichment_cache").
+		Where(sq.Eq{"key": key}).
+		Where("expires_at > NOW()").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+
+	logger := c.logger.WithFields(map[string]any{
+		"request": map[string]any{
+			"query": query,
+			"args": map[string]any{
+				"key": key,
+			},
+		},
+	})
+
+	if err != nil {
+		logger.Warnf("error on create sql query: %s", err)
+
+		return nil, false, err
+	}
+
+	var value []byte
+
+	if err := c.db.GetContext(ctx, &value, query, args...); err != nil {
+		if errpkg.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+
+		logger.Warnf("error on get cache entry: %s", err)
+
+		return nil, false, errors.
+			ErrInternal.
+			New("error on get cache entry").
+			Wrap(err)
+	}
+
+	return value, true, nil
+}
+
+func (c Cache) Set(
+	ctx context.Context,
+	key string,
+	value []byte,
+	ttl time.Duration,
+) error {
+
+	expiresAt := time.Now().Add(ttl)
+
+	query, args, err := sq.
+		Insert("enrichment_cache").
+		Columns("key", "value", "expires_at").
+		Values(key, string(value), expiresAt).
+		Suffix("ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+
+	logger := c.logger.WithFields(map[string]any{
+		"request": map[string]any{
+			"query": query,
+			"args": map[string]any{
+				"key":        key,
+				"expires_at": expiresAt,
+			},
+		},
+	})
+
+	if err != nil {
+		logger.Warnf("error on create sql query: %s", err)
+
+		return err
+	}
+
+	if _, err := c.db.ExecContext(ctx, query, args...); err != nil {
+		logger.Warnf("error on set cache entry: %s", err)
+
+		return errors.
+			ErrInternal.
+			New("error on set cache entry").
+			Wrap(err)
+	}
+
+	return nil
+}
+
+// DeleteExpired удаляет истёкшие записи и возвращает их количество
+func (c Cache) DeleteExpired(
+	ctx context.Context,
+) (int64, error) {
+
+	query, args, err := sq.
+		Delete("enrichment_cache").
+		Where("expires_at <= NOW()").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+
+	logger := c.logger.WithFields(map[string]any{
+		"request": map[string]any{
+			"query": query,
+		},
+	})
+
+	if err != nil {
+		logger.Warnf("error on create sql query: %s", err)
+
+		return 0, err
+	}
+
+	result, err := c.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.Warnf("error on delete expired cache entries: %s", err)
+
+		return 0, errors.
+			ErrInternal.
+			New("error on delete expired cache entries").
+			Wrap(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.
+			ErrInternal.
+			New("error on delete expired cache entries").
+			Wrap(err)
+	}
+
+	return deleted, nil
+}
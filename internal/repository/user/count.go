@@ -0,0 +1,165 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+)
+
+// Ниже этого порога оценка планировщика слишком неточна,
+// а точный подсчёт достаточно дёшев
+const exactCountThreshold = 10000
+
+// count возвращает число строк под фильтром и признак того,
+// что это оценка планировщика, а не точный подсчёт
+func (r Repository) count(
+	ctx context.Context,
+	filter dto.FilterDTO,
+	mode string,
+) (int, bool, error) {
+
+	switch mode {
+	case dto.CountExact:
+		total, err := r.exactCount(ctx, filter)
+
+		return total, false, err
+
+	case dto.CountEstimate:
+		estimate, err := r.estimateCount(ctx, filter)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if estimate >= exactCountThreshold {
+			return estimate, true, nil
+		}
+
+		total, err := r.exactCount(ctx, filter)
+
+		return total, false, err
+
+	default:
+		return 0, false, errors.
+			ErrInvalidValue.
+			New(fmt.Sprintf("unknown count mode %q", mode))
+	}
+}
+
+func (r Repository) exactCount(
+	ctx context.Context,
+	filter dto.FilterDTO,
+) (int, error) {
+
+	sb := sq.
+		Select("COUNT(*)").
+		From("users").
+		PlaceholderFormat(sq.Dollar)
+
+	sb, err := r.where(sb, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	query, args, err := sb.ToSql()
+
+	logger := r.logger.WithFields(map[string]any{
+		"request": map[string]any{
+			"query": query,
+		},
+	})
+
+	if err != nil {
+		logger.Warnf("error on create sql query: %s", err)
+
+		return 0, err
+	}
+
+	logger.Info(query)
+
+	var total int
+
+	if err := r.db.GetContext(ctx, &total, query, args...); err != nil {
+		logger.Warnf("error on count users: %s", err)
+
+		return 0, errors.
+			ErrInternal.
+			New("error on count users").
+			Wrap(err)
+	}
+
+	return total, nil
+}
+
+// estimateCount берёт число строк из плана запроса: на больших
+// таблицах это на порядки быстрее COUNT(*), но лишь приблизительно
+func (r Repository) estimateCount(
+	ctx context.Context,
+	filter dto.FilterDTO,
+) (int, error) {
+
+	sb := sq.
+		Select("1").
+		From("users").
+		PlaceholderFormat(sq.Dollar)
+
+	sb, err := r.where(sb, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	query, args, err := sb.ToSql()
+	if err != nil {
+		r.logger.Warnf("error on create sql query: %s", err)
+
+		return 0, err
+	}
+
+	query = fmt.Sprintf("EXPLAIN (FORMAT JSON) %s", query)
+
+	logger := r.logger.WithFields(map[string]any{
+		"request": map[string]any{
+			"query": query,
+		},
+	})
+
+	logger.Info(query)
+
+	var data []byte
+
+	if err := r.db.GetContext(ctx, &data, query, args...); err != nil {
+		logger.Warnf("error on estimate users count: %s", err)
+
+		return 0, errors.
+			ErrInternal.
+			New("error on estimate users count").
+			Wrap(err)
+	}
+
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+
+	if err := json.Unmarshal(data, &plans); err != nil {
+		logger.Warnf("can't parse query plan: %s", err)
+
+		return 0, errors.
+			ErrInternal.
+			New("can't parse query plan").
+			Wrap(err)
+	}
+
+	if len(plans) == 0 {
+		logger.Warn("query plan is empty")
+
+		return 0, errors.
+			ErrInternal.
+			New("query plan is empty")
+	}
+
+	return int(plans[0].Plan.Rows), nil
+}
@@ -0,0 +1,196 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"github.com/jmoiron/sqlx"
+)
+
+// countDB - минимальный драйвер базы: на EXPLAIN отвечает планом plan,
+// на COUNT(*) - числом total и запоминает вид каждого запроса
+type countDB struct {
+	plan    string
+	total   int
+	queries []string
+}
+
+func (c *countDB) Connect(context.Context) (driver.Conn, error) {
+	return c, nil
+}
+
+func (c *countDB) Driver() driver.Driver {
+	return nil
+}
+
+func (c *countDB) Prepare(string) (driver.Stmt, error) {
+	return nil, stderrors.New("prepared statements are not supported")
+}
+
+func (c *countDB) Close() error {
+	return nil
+}
+
+func (c *countDB) Begin() (driver.Tx, error) {
+	return nil, stderrors.New("transactions are not supported")
+}
+
+func (c *countDB) QueryContext(
+	_ context.Context,
+	query string,
+	_ []driver.NamedValue,
+) (driver.Rows, error) {
+
+	switch {
+	case strings.HasPrefix(query, "EXPLAIN"):
+		c.queries = append(c.queries, "explain")
+
+		return &countRows{column: "QUERY PLAN", value: []byte(c.plan)}, nil
+
+	case strings.HasPrefix(query, "SELECT COUNT(*)"):
+		c.queries = append(c.queries, "count")
+
+		return &countRows{column: "count", value: int64(c.total)}, nil
+
+	default:
+		return nil, fmt.Errorf("unexpected query %q", query)
+	}
+}
+
+// countRows - результат из одной строки с одним значением
+type countRows struct {
+	column string
+	value  driver.Value
+	read   bool
+}
+
+func (r *countRows) Columns() []string {
+	return []string{r.column}
+}
+
+func (r *countRows) Close() error {
+	return nil
+}
+
+func (r *countRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+
+	r.read = true
+	dest[0] = r.value
+
+	return nil
+}
+
+func planRows(rows int) string {
+	return fmt.Sprintf(`[{"Plan": {"Node Type": "Seq Scan", "Plan Rows": %d}}]`, rows)
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		plan     string
+		total    int
+		want     int
+		estimate bool
+		queries  []string
+		errType  *errpkg.Type
+	}{
+		{
+			name:    "exact",
+			mode:    dto.CountExact,
+			plan:    planRows(50000),
+			total:   42,
+			want:    42,
+			queries: []string{"count"},
+		},
+		{
+			name:     "estimate of a large table",
+			mode:     dto.CountEstimate,
+			plan:     planRows(50000),
+			total:    42,
+			want:     50000,
+			estimate: true,
+			queries:  []string{"explain"},
+		},
+		{
+			name:     "estimate at the threshold",
+			mode:     dto.CountEstimate,
+			plan:     planRows(exactCountThreshold),
+			want:     exactCountThreshold,
+			estimate: true,
+			queries:  []string{"explain"},
+		},
+		{
+			// Небольшую таблицу дешевле посчитать точно
+			name:    "estimate below the threshold",
+			mode:    dto.CountEstimate,
+			plan:    planRows(exactCountThreshold - 1),
+			total:   42,
+			want:    42,
+			queries: []string{"explain", "count"},
+		},
+		{
+			name:    "empty plan",
+			mode:    dto.CountEstimate,
+			plan:    "[]",
+			queries: []string{"explain"},
+			errType: errors.ErrInternal,
+		},
+		{
+			name:    "broken plan",
+			mode:    dto.CountEstimate,
+			plan:    `[{"Plan": `,
+			queries: []string{"explain"},
+			errType: errors.ErrInternal,
+		},
+		{
+			name:    "unknown mode",
+			mode:    "guess",
+			errType: errors.ErrInvalidValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &countDB{plan: tt.plan, total: tt.total}
+
+			r := New(sqlx.NewDb(sql.OpenDB(db), "postgres"), log.NewLogrusLogger())
+
+			total, estimate, err := r.count(context.Background(), dto.FilterDTO{}, tt.mode)
+
+			if !reflect.DeepEqual(db.queries, tt.queries) {
+				t.Errorf("queries = %q, want %q", db.queries, tt.queries)
+			}
+
+			if tt.errType != nil {
+				if !errpkg.Has(err, tt.errType) {
+					t.Errorf("count() error = %v, want %v", err, tt.errType)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("count() error: %s", err)
+			}
+
+			if total != tt.want || estimate != tt.estimate {
+				t.Errorf("count() = %d, %t, want %d, %t", total, estimate, tt.want, tt.estimate)
+			}
+		})
+	}
+}
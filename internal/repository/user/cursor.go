@@ -0,0 +1,135 @@
+package user
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+)
+
+// cursor - позиция в выборке: значения ключей сортировки и id строки.
+// Backward означает, что страница запрашивается перед этой строкой
+type cursor struct {
+	Sort     string `json:"s"`
+	Values   []any  `json:"v"`
+	ID       int    `json:"id"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor{}, errors.
+			ErrInvalidValue.
+			New("invalid cursor").
+			Wrap(err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var c cursor
+
+	if err := decoder.Decode(&c); err != nil {
+		return cursor{}, errors.
+			ErrInvalidValue.
+			New("invalid cursor").
+			Wrap(err)
+	}
+
+	return c, nil
+}
+
+func (s sortSpec) cursor(
+	user dto.User,
+	backward bool,
+) cursor {
+
+	values := make([]any, len(s.keys))
+	for i, key := range s.keys {
+		values[i] = sortValues[key.column](user)
+	}
+
+	return cursor{
+		Sort:     s.signature(),
+		Values:   values,
+		ID:       user.ID,
+		Backward: backward,
+	}
+}
+
+// after отбирает строки, следующие за курсором в порядке обхода:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... OR (k1 = v1 AND ... AND id > id0)
+func (s sortSpec) after(
+	c cursor,
+	backward bool,
+) (sq.Sqlizer, error) {
+
+	if c.Sort != s.signature() || len(c.Values) != len(s.keys) {
+		return nil, errors.
+			ErrInvalidValue.
+			New("cursor doesn't match sort order")
+	}
+
+	condition := sq.Or{}
+	equal := sq.And{}
+
+	for i, key := range s.keys {
+		if backward {
+			key = key.reverse()
+		}
+
+		value := c.Values[i]
+
+		if greater := key.greater(value); greater != nil {
+			condition = append(condition, append(clone(equal), greater))
+		}
+
+		equal = append(equal, sq.Eq{key.column: value})
+	}
+
+	var id sq.Sqlizer = sq.Gt{"id": c.ID}
+	if s.idDesc != backward {
+		id = sq.Lt{"id": c.ID}
+	}
+
+	return append(condition, append(clone(equal), id)), nil
+}
+
+// greater отбирает строки, стоящие строго после value по этому ключу,
+// nil - таких строк нет
+func (k sortKey) greater(
+	value any,
+) sq.Sqlizer {
+
+	if value == nil {
+		if k.nullsFirst {
+			return sq.NotEq{k.column: nil}
+		}
+
+		return nil
+	}
+
+	var greater sq.Sqlizer = sq.Gt{k.column: value}
+	if k.desc {
+		greater = sq.Lt{k.column: value}
+	}
+
+	if k.nullsFirst {
+		return greater
+	}
+
+	return sq.Or{greater, sq.Eq{k.column: nil}}
+}
+
+func clone(conditions sq.And) sq.And {
+	return append(sq.And{}, conditions...)
+}
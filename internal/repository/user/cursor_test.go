@@ -0,0 +1,212 @@
+package user
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	age, gender := 42, "male"
+
+	spec, err := newSortSpec(dto.SortDTO{Keys: []dto.SortKey{
+		{Field: "age", Order: dto.SortDesc},
+		{Field: "gender"},
+		{Field: "country"},
+		{Field: "surname"},
+	}})
+	if err != nil {
+		t.Fatalf("newSortSpec() error: %s", err)
+	}
+
+	user := dto.User{
+		ID:      7,
+		Surname: "Ushakov",
+		Age:     &age,
+		Gender:  &gender,
+	}
+
+	for _, backward := range []bool{false, true} {
+		encoded := encodeCursor(spec.cursor(user, backward))
+
+		decoded, err := decodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("decodeCursor() error: %s", err)
+		}
+
+		want := cursor{
+			Sort: spec.signature(),
+			// Числа декодируются без потери точности
+			Values:   []any{json.Number("42"), "male", nil, "Ushakov"},
+			ID:       7,
+			Backward: backward,
+		}
+
+		if !reflect.DeepEqual(decoded, want) {
+			t.Errorf("decodeCursor() = %#v, want %#v", decoded, want)
+		}
+	}
+}
+
+func TestCursorIsURLSafe(t *testing.T) {
+	encoded := encodeCursor(cursor{
+		Sort:   "name:false:false,id:false",
+		Values: []any{"???>>>~~~"},
+		ID:     1,
+	})
+
+	for _, r := range encoded {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			t.Fatalf("cursor %q contains %q", encoded, r)
+		}
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "not base64", value: "!!!"},
+		{name: "padded base64", value: base64.URLEncoding.EncodeToString([]byte(`{"id":1}`))},
+		{name: "not json", value: base64.RawURLEncoding.EncodeToString([]byte("id=1"))},
+		{name: "wrong types", value: base64.RawURLEncoding.EncodeToString([]byte(`{"id":"1"}`))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeCursor(tt.value)
+			if !errpkg.Has(err, errors.ErrInvalidValue) {
+				t.Errorf("decodeCursor() error = %v, want ErrInvalidValue", err)
+			}
+		})
+	}
+}
+
+func TestSortSpecAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		sort     dto.SortDTO
+		values   []any
+		backward bool
+		sql      string
+		args     []any
+	}{
+		{
+			name: "id only",
+			sql:  "((id > ?))",
+			args: []any{5},
+		},
+		{
+			name:     "id only backward",
+			backward: true,
+			sql:      "((id < ?))",
+			args:     []any{5},
+		},
+		{
+			name:   "ascending with nulls last",
+			sort:   dto.SortDTO{Keys: []dto.SortKey{{Field: "age"}}},
+			values: []any{30},
+			sql:    "(((age > ? OR age IS NULL)) OR (age = ? AND id > ?))",
+			args:   []any{30, 30, 5},
+		},
+		{
+			name:     "ascending backward",
+			sort:     dto.SortDTO{Keys: []dto.SortKey{{Field: "age"}}},
+			values:   []any{30},
+			backward: true,
+			sql:      "((age < ?) OR (age = ? AND id < ?))",
+			args:     []any{30, 30, 5},
+		},
+		{
+			name:   "null value with nulls last",
+			sort:   dto.SortDTO{Keys: []dto.SortKey{{Field: "age"}}},
+			values: []any{nil},
+			sql:    "((age IS NULL AND id > ?))",
+			args:   []any{5},
+		},
+		{
+			name:   "null value with nulls first",
+			sort:   dto.SortDTO{Keys: []dto.SortKey{{Field: "age", Order: dto.SortDesc}}},
+			values: []any{nil},
+			sql:    "((age IS NOT NULL) OR (age IS NULL AND id > ?))",
+			args:   []any{5},
+		},
+		{
+			name: "two keys",
+			sort: dto.SortDTO{Keys: []dto.SortKey{
+				{Field: "surname", Order: dto.SortDesc},
+				{Field: "name"},
+			}},
+			values: []any{"Ushakov", "Dmitriy"},
+			sql: "((surname < ?) OR " +
+				"(surname = ? AND (name > ? OR name IS NULL)) OR " +
+				"(surname = ? AND name = ? AND id > ?))",
+			args: []any{"Ushakov", "Ushakov", "Dmitriy", "Ushakov", "Dmitriy", 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := newSortSpec(tt.sort)
+			if err != nil {
+				t.Fatalf("newSortSpec() error: %s", err)
+			}
+
+			values := tt.values
+			if values == nil {
+				values = []any{}
+			}
+
+			after, err := spec.after(cursor{Sort: spec.signature(), Values: values, ID: 5}, tt.backward)
+			if err != nil {
+				t.Fatalf("after() error: %s", err)
+			}
+
+			sql, args, err := after.ToSql()
+			if err != nil {
+				t.Fatalf("ToSql() error: %s", err)
+			}
+
+			if sql != tt.sql {
+				t.Errorf("sql = %q, want %q", sql, tt.sql)
+			}
+
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestSortSpecAfterRejectsForeignCursor(t *testing.T) {
+	byAge, err := newSortSpec(dto.SortDTO{Keys: []dto.SortKey{{Field: "age"}}})
+	if err != nil {
+		t.Fatalf("newSortSpec() error: %s", err)
+	}
+
+	byName, err := newSortSpec(dto.SortDTO{Keys: []dto.SortKey{{Field: "name"}}})
+	if err != nil {
+		t.Fatalf("newSortSpec() error: %s", err)
+	}
+
+	cursors := map[string]cursor{
+		"another sort":      byName.cursor(dto.User{ID: 1, Name: "Ivan"}, false),
+		"missing values":    {Sort: byAge.signature(), ID: 1},
+		"too many values":   {Sort: byAge.signature(), Values: []any{1, 2}, ID: 1},
+		"missing signature": {Values: []any{1}, ID: 1},
+	}
+
+	for name, c := range cursors {
+		if _, err := byAge.after(c, false); !errpkg.Has(err, errors.ErrInvalidValue) {
+			t.Errorf("%s: after() error = %v, want ErrInvalidValue", name, err)
+		}
+	}
+}
@@ -4,131 +4,374 @@ import (
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"strconv"
 	"strings"
 )
 
+// filterField - столбец, по которому разрешено фильтровать:
+// допустимые операторы и приведение значения к типу столбца.
+// У fold-столбцов eq, ne, prefix и contains не различают регистр
+// и диакритику, для них есть триграммные индексы (миграция 08)
+type filterField struct {
+	operators map[string]bool
+	value     func(string) (any, error)
+	fold      bool
+}
+
+var (
+	textOperators = map[string]bool{
+		dto.OpEq: true, dto.OpNe: true,
+		dto.OpIn: true, dto.OpNotIn: true,
+		dto.OpPrefix: true, dto.OpContains: true, dto.OpExact: true,
+		dto.OpIsNull: true,
+	}
+
+	nameOperators = map[string]bool{
+		dto.OpEq: true, dto.OpNe: true,
+		dto.OpIn: true, dto.OpNotIn: true,
+		dto.OpPrefix: true, dto.OpContains: true, dto.OpExact: true,
+		dto.OpIsNull: true, dto.OpSimilar: true,
+	}
+
+	numberOperators = map[string]bool{
+		dto.OpEq: true, dto.OpNe: true,
+		dto.OpGt: true, dto.OpGe: true, dto.OpLt: true, dto.OpLe: true,
+		dto.OpIn: true, dto.OpNotIn: true,
+		dto.OpBetween: true,
+		dto.OpIsNull:  true,
+	}
+)
+
+var filterFields = map[string]filterField{
+	"name":       {operators: nameOperators, value: textValue(nil), fold: true},
+	"surname":    {operators: nameOperators, value: textValue(nil), fold: true},
+	"patronymic": {operators: nameOperators, value: textValue(nil), fold: true},
+	"gender":     {operators: textOperators, value: textValue(strings.ToLower)},
+	"country":    {operators: textOperators, value: textValue(strings.ToUpper)},
+	"age":        {operators: numberOperators, value: numberValue},
+}
+
+func textValue(
+	normalize func(string) string,
+) func(string) (any, error) {
+
+	return func(value string) (any, error) {
+		if normalize != nil {
+			value = normalize(value)
+		}
+
+		return value, nil
+	}
+}
+
+func numberValue(value string) (any, error) {
+	number, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return nil, errors.
+			ErrInvalidValue.
+			New(fmt.Sprintf("%q isn't a number", value)).
+			Wrap(err)
+	}
+
+	return number, nil
+}
+
 func (r Repository) where(
 	builder sq.SelectBuilder,
 	filter dto.FilterDTO,
-) sq.SelectBuilder {
+) (sq.SelectBuilder, error) {
+
+	for _, condition := range legacyConditions(filter) {
+		sqlizer, err := conditionSql(condition)
+		if err != nil {
+			return builder, err
+		}
 
-	builder = r.whereName(builder, filter.Name)
-	builder = r.whereSurname(builder, filter.Surname)
-	builder = r.wherePatronymic(builder, filter.Patronymic)
-	builder = r.whereAge(builder, filter.Age, filter.AgeSort)
-	builder = r.whereGender(builder, filter.Gender)
-	builder = r.whereCountry(builder, filter.Country)
+		builder = builder.Where(sqlizer)
+	}
+
+	for _, condition := range filter.Conditions {
+		sqlizer, err := conditionSql(condition)
+		if err != nil {
+			return builder, err
+		}
 
-	return builder
+		builder = builder.Where(sqlizer)
+	}
+
+	return r.whereAfterID(builder, filter.AfterID), nil
 }
 
-func (r Repository) whereName(
-	builder sq.SelectBuilder,
-	name string,
-) sq.SelectBuilder {
+// legacyConditions переводит прежние поля фильтра в условия
+func legacyConditions(
+	filter dto.FilterDTO,
+) []dto.Condition {
 
-	if len(strings.TrimSpace(name)) == 0 {
-		return builder
+	conditions := make([]dto.Condition, 0)
+
+	text := map[string]string{
+		"name":       filter.Name,
+		"surname":    filter.Surname,
+		"patronymic": filter.Patronymic,
 	}
 
-	return builder.Where(
-		sq.Like{
-			"name": fmt.Sprintf("%%%s%%", name),
-		},
-	)
+	for _, field := range []string{"name", "surname", "patronymic"} {
+		if len(strings.TrimSpace(text[field])) == 0 {
+			continue
+		}
+
+		conditions = append(conditions, dto.Condition{
+			Field:    field,
+			Operator: dto.OpContains,
+			Values:   []string{text[field]},
+		})
+	}
+
+	// Неизвестный оператор возраста, как и раньше, отключает фильтр
+	switch filter.AgeSort {
+	case dto.OpEq, dto.OpNe, dto.OpGt, dto.OpGe, dto.OpLt, dto.OpLe:
+		conditions = append(conditions, dto.Condition{
+			Field:    "age",
+			Operator: filter.AgeSort,
+			Values:   []string{strconv.Itoa(filter.Age)},
+		})
+	}
+
+	if len(filter.Gender) > 0 {
+		conditions = append(conditions, dto.Condition{
+			Field:    "gender",
+			Operator: dto.OpIn,
+			Values:   filter.Gender,
+		})
+	}
+
+	if len(filter.Country) > 0 {
+		conditions = append(conditions, dto.Condition{
+			Field:    "country",
+			Operator: dto.OpIn,
+			Values:   filter.Country,
+		})
+	}
+
+	return conditions
 }
-func (r Repository) whereSurname(
-	builder sq.SelectBuilder,
-	surname string,
-) sq.SelectBuilder {
 
-	if len(strings.TrimSpace(surname)) == 0 {
-		return builder
+// conditionSql принимает только поля из filterFields: имена
+// столбцов подставляются в запрос как есть
+func conditionSql(
+	condition dto.Condition,
+) (sq.Sqlizer, error) {
+
+	column := condition.Field
+
+	field, ok := filterFields[column]
+	if !ok {
+		return nil, errors.
+			ErrInvalidValue.
+			New(fmt.Sprintf("unknown filter field %q", column))
 	}
 
-	return builder.Where(
-		sq.Like{
-			"surname": fmt.Sprintf("%%%s%%", surname),
-		},
-	)
+	if !field.operators[condition.Operator] {
+		return nil, errors.
+			ErrInvalidValue.
+			New(fmt.Sprintf("operator %q isn't supported for %q", condition.Operator, column))
+	}
+
+	if condition.Operator == dto.OpIsNull {
+		return isNullSql(column, condition.Values)
+	}
+
+	values := make([]any, len(condition.Values))
+
+	for i, value := range condition.Values {
+		v, err := field.value(value)
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = v
+	}
+
+	switch condition.Operator {
+	case dto.OpIn, dto.OpNotIn:
+		if len(values) == 0 {
+			return nil, valuesCountError(condition, "at least one")
+		}
+
+		if condition.Operator == dto.OpIn {
+			return sq.Eq{column: values}, nil
+		}
+
+		return sq.NotEq{column: values}, nil
+
+	case dto.OpBetween:
+		if len(values) != 2 {
+			return nil, valuesCountError(condition, "two")
+		}
+
+		return sq.Expr(
+			fmt.Sprintf("%s BETWEEN ? AND ?", column),
+			values[0], values[1],
+		), nil
+	}
+
+	if len(values) != 1 {
+		return nil, valuesCountError(condition, "one")
+	}
+
+	value := values[0]
+
+	if field.fold {
+		switch condition.Operator {
+		case dto.OpEq:
+			return foldLike(column, escapeLike(value.(string)), false), nil
+		case dto.OpNe:
+			return foldLike(column, escapeLike(value.(string)), true), nil
+		case dto.OpPrefix:
+			return foldLike(column, fmt.Sprintf("%s%%", escapeLike(value.(string))), false), nil
+		case dto.OpContains:
+			return foldLike(column, fmt.Sprintf("%%%s%%", escapeLike(value.(string))), false), nil
+		case dto.OpSimilar:
+			// Порог сходства задаёт pg_trgm.similarity_threshold, по умолчанию 0.3
+			return sq.Expr(
+				fmt.Sprintf("immutable_unaccent(%s) %% immutable_unaccent(?)", column),
+				value,
+			), nil
+		}
+	}
+
+	switch condition.Operator {
+	case dto.OpEq, dto.OpExact:
+		return sq.Eq{column: value}, nil
+	case dto.OpNe:
+		return sq.NotEq{column: value}, nil
+	case dto.OpGt:
+		return sq.Gt{column: value}, nil
+	case dto.OpGe:
+		return sq.GtOrEq{column: value}, nil
+	case dto.OpLt:
+		return sq.Lt{column: value}, nil
+	case dto.OpLe:
+		return sq.LtOrEq{column: value}, nil
+	case dto.OpPrefix:
+		return sq.Like{column: fmt.Sprintf("%s%%", escapeLike(value.(string)))}, nil
+	default:
+		return sq.Like{column: fmt.Sprintf("%%%s%%", escapeLike(value.(string)))}, nil
+	}
 }
 
-func (r Repository) wherePatronymic(
-	builder sq.SelectBuilder,
-	patronymic string,
-) sq.SelectBuilder {
+func isNullSql(
+	column string,
+	values []string,
+) (sq.Sqlizer, error) {
 
-	if len(strings.TrimSpace(patronymic)) == 0 {
-		return builder
+	if len(values) > 1 {
+		return nil, errors.
+			ErrInvalidValue.
+			New(fmt.Sprintf("operator %q expects at most one value", dto.OpIsNull))
 	}
 
-	return builder.Where(
-		sq.Like{
-			"patronymic": fmt.Sprintf("%%%s%%", patronymic),
-		},
-	)
+	isNull := true
+
+	if len(values) == 1 && values[0] != "" {
+		value, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return nil, errors.
+				ErrInvalidValue.
+				New(fmt.Sprintf("%q isn't a boolean", values[0])).
+				Wrap(err)
+		}
+
+		isNull = value
+	}
+
+	if isNull {
+		return sq.Eq{column: nil}, nil
+	}
+
+	return sq.NotEq{column: nil}, nil
 }
 
-func (r Repository) whereAge(
-	builder sq.SelectBuilder,
-	age int,
-	sortParam string,
-) sq.SelectBuilder {
+func valuesCountError(
+	condition dto.Condition,
+	expected string,
+) error {
 
-	switch sortParam {
-	case "eq":
-		return builder.Where(sq.Eq{"age": age})
-	case "ne":
-		return builder.Where(sq.NotEq{"age": age})
-	case "gt":
-		return builder.Where(sq.Gt{"age": age})
-	case "ge":
-		return builder.Where(sq.GtOrEq{"age": age})
-	case "lt":
-		return builder.Where(sq.Lt{"age": age})
-	case "le":
-		return builder.Where(sq.LtOrEq{"age": age})
-	default:
-		return builder
+	return errors.
+		ErrInvalidValue.
+		New(fmt.Sprintf(
+			"operator %q for %q expects %s value(s), got %d",
+			condition.Operator, condition.Field, expected, len(condition.Values),
+		))
+}
+
+func foldLike(
+	column string,
+	pattern string,
+	not bool,
+) sq.Sqlizer {
+
+	operator := "ILIKE"
+	if not {
+		operator = "NOT ILIKE"
 	}
+
+	return sq.Expr(
+		fmt.Sprintf("immutable_unaccent(%s) %s immutable_unaccent(?)", column, operator),
+		pattern,
+	)
 }
 
-func (r Repository) whereGender(
-	builder sq.SelectBuilder,
-	genders []string,
-) sq.SelectBuilder {
+// similarityRank - суммарное сходство с условиями similar для
+// ранжирования выборки, nil - таких условий нет
+func similarityRank(
+	filter dto.FilterDTO,
+) sq.Sqlizer {
 
-	if len(genders) == 0 {
-		return builder
+	var (
+		terms []string
+		args  []any
+	)
+
+	for _, condition := range filter.Conditions {
+		field, ok := filterFields[condition.Field]
+		if !ok || !field.fold || condition.Operator != dto.OpSimilar || len(condition.Values) != 1 {
+			continue
+		}
+
+		terms = append(terms, fmt.Sprintf(
+			"similarity(immutable_unaccent(%s), immutable_unaccent(?))",
+			condition.Field,
+		))
+		args = append(args, condition.Values[0])
 	}
 
-	orCondition := sq.Or{}
-	for _, gender := range genders {
-		orCondition = append(
-			orCondition,
-			sq.Eq{"gender": strings.ToLower(gender)},
-		)
+	if len(terms) == 0 {
+		return nil
 	}
 
-	return builder.Where(orCondition)
+	return sq.Expr(fmt.Sprintf("%s DESC", strings.Join(terms, " + ")), args...)
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы значение
+// сравнивалось буквально
+func escapeLike(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		`%`, `\%`,
+		`_`, `\_`,
+	).Replace(value)
 }
 
-func (r Repository) whereCountry(
+func (r Repository) whereAfterID(
 	builder sq.SelectBuilder,
-	countries []string,
+	afterID int,
 ) sq.SelectBuilder {
 
-	if len(countries) == 0 {
+	if afterID <= 0 {
 		return builder
 	}
 
-	orCondition := sq.Or{}
-	for _, country := range countries {
-		orCondition = append(
-			orCondition,
-			sq.Eq{"country": strings.ToUpper(country)},
-		)
-	}
-
-	return builder.Where(orCondition)
+	return builder.Where(sq.Gt{"id": afterID})
 }
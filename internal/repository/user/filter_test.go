@@ -0,0 +1,323 @@
+package user
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+)
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "Ivan", want: "Ivan"},
+		{value: "100%", want: `100\%`},
+		{value: "a_b", want: `a\_b`},
+		{value: `a\b`, want: `a\\b`},
+		{value: `%_\`, want: `\%\_\\`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeLike(tt.value); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestConditionSql(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition dto.Condition
+		sql       string
+		args      []any
+	}{
+		{
+			name:      "fold eq",
+			condition: dto.Condition{Field: "name", Operator: dto.OpEq, Values: []string{"Ivan"}},
+			sql:       "immutable_unaccent(name) ILIKE immutable_unaccent(?)",
+			args:      []any{"Ivan"},
+		},
+		{
+			name:      "fold ne escapes pattern",
+			condition: dto.Condition{Field: "surname", Operator: dto.OpNe, Values: []string{"a_b"}},
+			sql:       "immutable_unaccent(surname) NOT ILIKE immutable_unaccent(?)",
+			args:      []any{`a\_b`},
+		},
+		{
+			name:      "fold prefix",
+			condition: dto.Condition{Field: "name", Operator: dto.OpPrefix, Values: []string{"Iv%"}},
+			sql:       "immutable_unaccent(name) ILIKE immutable_unaccent(?)",
+			args:      []any{`Iv\%%`},
+		},
+		{
+			name:      "fold contains",
+			condition: dto.Condition{Field: "patronymic", Operator: dto.OpContains, Values: []string{"vich"}},
+			sql:       "immutable_unaccent(patronymic) ILIKE immutable_unaccent(?)",
+			args:      []any{"%vich%"},
+		},
+		{
+			name:      "exact is case sensitive",
+			condition: dto.Condition{Field: "name", Operator: dto.OpExact, Values: []string{"Ivan"}},
+			sql:       "name = ?",
+			args:      []any{"Ivan"},
+		},
+		{
+			name:      "similar",
+			condition: dto.Condition{Field: "surname", Operator: dto.OpSimilar, Values: []string{"Ushakof"}},
+			sql:       "immutable_unaccent(surname) % immutable_unaccent(?)",
+			args:      []any{"Ushakof"},
+		},
+		{
+			name:      "gender is normalized",
+			condition: dto.Condition{Field: "gender", Operator: dto.OpIn, Values: []string{"Male", "FEMALE"}},
+			sql:       "gender IN (?,?)",
+			args:      []any{"male", "female"},
+		},
+		{
+			name:      "country is normalized",
+			condition: dto.Condition{Field: "country", Operator: dto.OpNotIn, Values: []string{"ru"}},
+			sql:       "country NOT IN (?)",
+			args:      []any{"RU"},
+		},
+		{
+			name:      "country prefix is literal",
+			condition: dto.Condition{Field: "country", Operator: dto.OpPrefix, Values: []string{"r_"}},
+			sql:       "country LIKE ?",
+			args:      []any{`R\_%`},
+		},
+		{
+			name:      "age greater",
+			condition: dto.Condition{Field: "age", Operator: dto.OpGt, Values: []string{" 30 "}},
+			sql:       "age > ?",
+			args:      []any{30},
+		},
+		{
+			name:      "age between",
+			condition: dto.Condition{Field: "age", Operator: dto.OpBetween, Values: []string{"18", "65"}},
+			sql:       "age BETWEEN ? AND ?",
+			args:      []any{18, 65},
+		},
+		{
+			name:      "is null without value",
+			condition: dto.Condition{Field: "age", Operator: dto.OpIsNull},
+			sql:       "age IS NULL",
+		},
+		{
+			name:      "is not null",
+			condition: dto.Condition{Field: "country", Operator: dto.OpIsNull, Values: []string{"false"}},
+			sql:       "country IS NOT NULL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sqlizer, err := conditionSql(tt.condition)
+			if err != nil {
+				t.Fatalf("conditionSql() error: %s", err)
+			}
+
+			sql, args, err := sqlizer.ToSql()
+			if err != nil {
+				t.Fatalf("ToSql() error: %s", err)
+			}
+
+			if sql != tt.sql {
+				t.Errorf("sql = %q, want %q", sql, tt.sql)
+			}
+
+			if len(args) != 0 || len(tt.args) != 0 {
+				if !reflect.DeepEqual(args, tt.args) {
+					t.Errorf("args = %#v, want %#v", args, tt.args)
+				}
+			}
+		})
+	}
+}
+
+func TestConditionSqlRejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition dto.Condition
+		message   string
+	}{
+		{
+			name:      "unknown field",
+			condition: dto.Condition{Field: "id; DROP TABLE users", Operator: dto.OpEq, Values: []string{"1"}},
+			message:   "unknown filter field",
+		},
+		{
+			name:      "operator of another type",
+			condition: dto.Condition{Field: "age", Operator: dto.OpContains, Values: []string{"3"}},
+			message:   `operator "contains" isn't supported for "age"`,
+		},
+		{
+			name:      "similar for gender",
+			condition: dto.Condition{Field: "gender", Operator: dto.OpSimilar, Values: []string{"male"}},
+			message:   `operator "similar" isn't supported for "gender"`,
+		},
+		{
+			name:      "unknown operator",
+			condition: dto.Condition{Field: "name", Operator: "regex", Values: []string{".*"}},
+			message:   `operator "regex" isn't supported`,
+		},
+		{
+			name:      "in without values",
+			condition: dto.Condition{Field: "gender", Operator: dto.OpIn},
+			message:   "expects at least one value(s), got 0",
+		},
+		{
+			name:      "between with one value",
+			condition: dto.Condition{Field: "age", Operator: dto.OpBetween, Values: []string{"18"}},
+			message:   "expects two value(s), got 1",
+		},
+		{
+			name:      "eq with two values",
+			condition: dto.Condition{Field: "name", Operator: dto.OpEq, Values: []string{"Ivan", "Petr"}},
+			message:   "expects one value(s), got 2",
+		},
+		{
+			name:      "eq without values",
+			condition: dto.Condition{Field: "age", Operator: dto.OpEq},
+			message:   "expects one value(s), got 0",
+		},
+		{
+			name:      "age isn't a number",
+			condition: dto.Condition{Field: "age", Operator: dto.OpLt, Values: []string{"old"}},
+			message:   `"old" isn't a number`,
+		},
+		{
+			name:      "is null with two values",
+			condition: dto.Condition{Field: "age", Operator: dto.OpIsNull, Values: []string{"true", "false"}},
+			message:   "expects at most one value",
+		},
+		{
+			name:      "is null isn't a boolean",
+			condition: dto.Condition{Field: "age", Operator: dto.OpIsNull, Values: []string{"maybe"}},
+			message:   `"maybe" isn't a boolean`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := conditionSql(tt.condition)
+			if err == nil {
+				t.Fatal("conditionSql() error is nil")
+			}
+
+			if !errpkg.Has(err, errors.ErrInvalidValue) {
+				t.Errorf("error %q isn't ErrInvalidValue", err)
+			}
+
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error %q doesn't contain %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func TestLegacyConditions(t *testing.T) {
+	filter := dto.FilterDTO{
+		Name:    "Iv",
+		Surname: "  ",
+		Age:     30,
+		AgeSort: dto.OpGe,
+		Gender:  []string{"male"},
+		Country: []string{"RU", "BY"},
+	}
+
+	want := []dto.Condition{
+		{Field: "name", Operator: dto.OpContains, Values: []string{"Iv"}},
+		{Field: "age", Operator: dto.OpGe, Values: []string{"30"}},
+		{Field: "gender", Operator: dto.OpIn, Values: []string{"male"}},
+		{Field: "country", Operator: dto.OpIn, Values: []string{"RU", "BY"}},
+	}
+
+	if got := legacyConditions(filter); !reflect.DeepEqual(got, want) {
+		t.Errorf("legacyConditions() = %#v, want %#v", got, want)
+	}
+
+	// Неизвестный оператор возраста отключает фильтр
+	filter = dto.FilterDTO{Age: 30, AgeSort: "around"}
+
+	if got := legacyConditions(filter); len(got) != 0 {
+		t.Errorf("legacyConditions() = %#v, want no conditions", got)
+	}
+}
+
+func TestWhere(t *testing.T) {
+	filter := dto.FilterDTO{
+		Gender: []string{"female"},
+		Conditions: []dto.Condition{
+			{Field: "age", Operator: dto.OpLe, Values: []string{"40"}},
+		},
+		AfterID: 10,
+	}
+
+	builder, err := Repository{}.where(sq.Select("id").From("users"), filter)
+	if err != nil {
+		t.Fatalf("where() error: %s", err)
+	}
+
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		t.Fatalf("ToSql() error: %s", err)
+	}
+
+	wantSql := "SELECT id FROM users WHERE gender IN (?) AND age <= ? AND id > ?"
+	if sql != wantSql {
+		t.Errorf("sql = %q, want %q", sql, wantSql)
+	}
+
+	if want := []any{"female", 40, 10}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %#v, want %#v", args, want)
+	}
+}
+
+func TestSimilarityRank(t *testing.T) {
+	if rank := similarityRank(dto.FilterDTO{}); rank != nil {
+		t.Errorf("similarityRank() = %v, want nil", rank)
+	}
+
+	filter := dto.FilterDTO{
+		Conditions: []dto.Condition{
+			{Field: "surname", Operator: dto.OpSimilar, Values: []string{"Ushakof"}},
+			{Field: "age", Operator: dto.OpGt, Values: []string{"18"}},
+			{Field: "name", Operator: dto.OpSimilar, Values: []string{"Dmitri"}},
+		},
+	}
+
+	sql, args, err := similarityRank(filter).ToSql()
+	if err != nil {
+		t.Fatalf("ToSql() error: %s", err)
+	}
+
+	wantSql := "similarity(immutable_unaccent(surname), immutable_unaccent(?)) + " +
+		"similarity(immutable_unaccent(name), immutable_unaccent(?)) DESC"
+	if sql != wantSql {
+		t.Errorf("sql = %q, want %q", sql, wantSql)
+	}
+
+	if want := []any{"Ushakof", "Dmitri"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %#v, want %#v", args, want)
+	}
+}
+
+func TestGetPageRejectsSimilar(t *testing.T) {
+	filter := dto.FilterDTO{
+		Conditions: []dto.Condition{
+			{Field: "name", Operator: dto.OpSimilar, Values: []string{"Dmitri"}},
+		},
+	}
+
+	_, err := Repository{}.GetPage(context.Background(), dto.PageDTO{Limit: 10}, filter, dto.SortDTO{})
+	if !errpkg.Has(err, errors.ErrInvalidValue) {
+		t.Errorf("GetPage() error = %v, want ErrInvalidValue", err)
+	}
+}
@@ -0,0 +1,163 @@
+package user
+
+import (
+	"fmt"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+)
+
+// sortValues - столбцы, по которым разрешена сортировка,
+// и значения этих столбцов у пользователя
+var sortValues = map[string]func(dto.User) any{
+	"id":         func(u dto.User) any { return u.ID },
+	"name":       func(u dto.User) any { return u.Name },
+	"surname":    func(u dto.User) any { return u.Surname },
+	"patronymic": func(u dto.User) any { return u.Patronymic },
+	"age":        func(u dto.User) any { return deref(u.Age) },
+	"gender":     func(u dto.User) any { return deref(u.Gender) },
+	"country":    func(u dto.User) any { return deref(u.Country) },
+}
+
+func deref[T any](value *T) any {
+	if value == nil {
+		return nil
+	}
+
+	return *value
+}
+
+type sortKey struct {
+	column     string
+	desc       bool
+	nullsFirst bool
+}
+
+// reverse - тот же ключ при обходе выборки в обратном порядке
+func (k sortKey) reverse() sortKey {
+	return sortKey{
+		column:     k.column,
+		desc:       !k.desc,
+		nullsFirst: !k.nullsFirst,
+	}
+}
+
+// sortSpec - проверенный порядок строк; id замыкает его,
+// чтобы позиция любой строки была однозначной
+type sortSpec struct {
+	keys   []sortKey
+	idDesc bool
+}
+
+// newSortSpec принимает только столбцы из sortValues: имена
+// столбцов подставляются в запрос как есть
+func newSortSpec(
+	sort dto.SortDTO,
+) (sortSpec, error) {
+
+	spec := sortSpec{}
+	seen := make(map[string]bool, len(sort.Keys))
+
+	for _, key := range sort.Keys {
+		if _, ok := sortValues[key.Field]; !ok {
+			return sortSpec{}, errors.
+				ErrInvalidValue.
+				New(fmt.Sprintf("unknown sort field %q", key.Field))
+		}
+
+		if seen[key.Field] {
+			return sortSpec{}, errors.
+				ErrInvalidValue.
+				New(fmt.Sprintf("duplicate sort field %q", key.Field))
+		}
+
+		seen[key.Field] = true
+
+		var desc bool
+
+		switch key.Order {
+		case "", dto.SortAsc:
+		case dto.SortDesc:
+			desc = true
+		default:
+			return sortSpec{}, errors.
+				ErrInvalidValue.
+				New(fmt.Sprintf("unknown sort order %q", key.Order))
+		}
+
+		// Ключи после id уже ни на что не влияют
+		if key.Field == "id" {
+			spec.idDesc = desc
+
+			return spec, nil
+		}
+
+		nullsFirst := desc
+
+		switch key.Nulls {
+		case "":
+		case dto.NullsFirst:
+			nullsFirst = true
+		case dto.NullsLast:
+			nullsFirst = false
+		default:
+			return sortSpec{}, errors.
+				ErrInvalidValue.
+				New(fmt.Sprintf("unknown nulls order %q", key.Nulls))
+		}
+
+		spec.keys = append(spec.keys, sortKey{
+			column:     key.Field,
+			desc:       desc,
+			nullsFirst: nullsFirst,
+		})
+	}
+
+	return spec, nil
+}
+
+func (s sortSpec) orderBy(
+	backward bool,
+) []string {
+
+	orderBy := make([]string, 0, len(s.keys)+1)
+
+	for _, key := range s.keys {
+		if backward {
+			key = key.reverse()
+		}
+
+		orderBy = append(orderBy, fmt.Sprintf(
+			"%s %s NULLS %s",
+			key.column, direction(key.desc), nulls(key.nullsFirst),
+		))
+	}
+
+	return append(orderBy, fmt.Sprintf("id %s", direction(s.idDesc != backward)))
+}
+
+// signature не даёт применить курсор к выборке с другим порядком
+func (s sortSpec) signature() string {
+	signature := ""
+
+	for _, key := range s.keys {
+		signature += fmt.Sprintf("%s:%t:%t,", key.column, key.desc, key.nullsFirst)
+	}
+
+	return fmt.Sprintf("%sid:%t", signature, s.idDesc)
+}
+
+func direction(desc bool) string {
+	if desc {
+		return "DESC"
+	}
+
+	return "ASC"
+}
+
+func nulls(first bool) string {
+	if first {
+		return "FIRST"
+	}
+
+	return "LAST"
+}
@@ -0,0 +1,160 @@
+package user
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+)
+
+func TestSortSpecOrderBy(t *testing.T) {
+	tests := []struct {
+		name     string
+		sort     dto.SortDTO
+		forward  []string
+		backward []string
+	}{
+		{
+			name:     "empty sort orders by id",
+			sort:     dto.SortDTO{},
+			forward:  []string{"id ASC"},
+			backward: []string{"id DESC"},
+		},
+		{
+			name: "ascending puts nulls last",
+			sort: dto.SortDTO{Keys: []dto.SortKey{
+				{Field: "age"},
+			}},
+			forward:  []string{"age ASC NULLS LAST", "id ASC"},
+			backward: []string{"age DESC NULLS FIRST", "id DESC"},
+		},
+		{
+			name: "descending puts nulls first",
+			sort: dto.SortDTO{Keys: []dto.SortKey{
+				{Field: "country", Order: dto.SortDesc},
+			}},
+			forward:  []string{"country DESC NULLS FIRST", "id ASC"},
+			backward: []string{"country ASC NULLS LAST", "id DESC"},
+		},
+		{
+			name: "explicit nulls order",
+			sort: dto.SortDTO{Keys: []dto.SortKey{
+				{Field: "gender", Order: dto.SortAsc, Nulls: dto.NullsFirst},
+				{Field: "surname", Order: dto.SortDesc, Nulls: dto.NullsLast},
+			}},
+			forward:  []string{"gender ASC NULLS FIRST", "surname DESC NULLS LAST", "id ASC"},
+			backward: []string{"gender DESC NULLS LAST", "surname ASC NULLS FIRST", "id DESC"},
+		},
+		{
+			name: "keys after id are ignored",
+			sort: dto.SortDTO{Keys: []dto.SortKey{
+				{Field: "name"},
+				{Field: "id", Order: dto.SortDesc},
+				{Field: "age"},
+			}},
+			forward:  []string{"name ASC NULLS LAST", "id DESC"},
+			backward: []string{"name DESC NULLS FIRST", "id ASC"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := newSortSpec(tt.sort)
+			if err != nil {
+				t.Fatalf("newSortSpec() error: %s", err)
+			}
+
+			if got := spec.orderBy(false); !reflect.DeepEqual(got, tt.forward) {
+				t.Errorf("orderBy(false) = %q, want %q", got, tt.forward)
+			}
+
+			if got := spec.orderBy(true); !reflect.DeepEqual(got, tt.backward) {
+				t.Errorf("orderBy(true) = %q, want %q", got, tt.backward)
+			}
+		})
+	}
+}
+
+func TestNewSortSpecRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []dto.SortKey
+		message string
+	}{
+		{
+			name:    "unknown field",
+			keys:    []dto.SortKey{{Field: "age; DROP TABLE users"}},
+			message: "unknown sort field",
+		},
+		{
+			name:    "column outside whitelist",
+			keys:    []dto.SortKey{{Field: "enrichment_status"}},
+			message: `unknown sort field "enrichment_status"`,
+		},
+		{
+			name:    "duplicate field",
+			keys:    []dto.SortKey{{Field: "age"}, {Field: "age", Order: dto.SortDesc}},
+			message: `duplicate sort field "age"`,
+		},
+		{
+			name:    "unknown order",
+			keys:    []dto.SortKey{{Field: "name", Order: "random"}},
+			message: `unknown sort order "random"`,
+		},
+		{
+			name:    "unknown nulls order",
+			keys:    []dto.SortKey{{Field: "name", Nulls: "middle"}},
+			message: `unknown nulls order "middle"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newSortSpec(dto.SortDTO{Keys: tt.keys})
+			if err == nil {
+				t.Fatal("newSortSpec() error is nil")
+			}
+
+			if !errpkg.Has(err, errors.ErrInvalidValue) {
+				t.Errorf("error %q isn't ErrInvalidValue", err)
+			}
+
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error %q doesn't contain %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func TestSortSpecSignature(t *testing.T) {
+	signatures := map[string]string{}
+
+	sorts := map[string]dto.SortDTO{
+		"id":              {},
+		"age asc":         {Keys: []dto.SortKey{{Field: "age"}}},
+		"age desc":        {Keys: []dto.SortKey{{Field: "age", Order: dto.SortDesc}}},
+		"age nulls first": {Keys: []dto.SortKey{{Field: "age", Nulls: dto.NullsFirst}}},
+		"id desc":         {Keys: []dto.SortKey{{Field: "id", Order: dto.SortDesc}}},
+		"age, name":       {Keys: []dto.SortKey{{Field: "age"}, {Field: "name"}}},
+		"name, age":       {Keys: []dto.SortKey{{Field: "name"}, {Field: "age"}}},
+		"age, id desc":    {Keys: []dto.SortKey{{Field: "age"}, {Field: "id", Order: dto.SortDesc}}},
+	}
+
+	for name, sort := range sorts {
+		spec, err := newSortSpec(sort)
+		if err != nil {
+			t.Fatalf("newSortSpec(%s) error: %s", name, err)
+		}
+
+		signature := spec.signature()
+
+		if other, ok := signatures[signature]; ok {
+			t.Errorf("%q and %q have the same signature %q", name, other, signature)
+		}
+
+		signatures[signature] = name
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/jackvonhouse/enrichment/pkg/log"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
+	"time"
 )
 
 type Repository struct {
@@ -33,19 +34,23 @@ func New(
 func (r Repository) Create(
 	ctx context.Context,
 	create dto.CreateDTO,
-	enrichment dto.EnrichmentDTO,
 ) (int, error) {
 
+	var countryHint *string
+	if create.CountryHint != "" {
+		countryHint = &create.CountryHint
+	}
+
 	query, args, err := sq.
 		Insert("users").
-		Columns(
-			"name", "surname", "patronymic",
-			"age", "gender", "country",
-		).
-		Values(
-			create.Name, create.Surname, create.Patronymic,
-			enrichment.Age, enrichment.Gender, enrichment.Country,
-		).
+		SetMap(map[string]any{
+			"name":                       create.Name,
+			"surname":                    create.Surname,
+			"patronymic":                 create.Patronymic,
+			"country_hint":               countryHint,
+			"enrichment_status":          dto.EnrichmentPending,
+			"enrichment_next_attempt_at": sq.Expr("NOW()"),
+		}).
 		Suffix("RETURNING id").
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
@@ -54,12 +59,10 @@ func (r Repository) Create(
 		"request": map[string]any{
 			"query": query,
 			"args": map[string]any{
-				"name":       create.Name,
-				"surname":    create.Surname,
-				"patronymic": create.Patronymic,
-				"age":        enrichment.Age,
-				"gender":     enrichment.Gender,
-				"country":    enrichment.Country,
+				"name":         create.Name,
+				"surname":      create.Surname,
+				"patronymic":   create.Patronymic,
+				"country_hint": create.CountryHint,
 			},
 		},
 	})
@@ -91,42 +94,406 @@ func (r Repository) Create(
 					ErrNotFound.
 					New("user not found").
 					Wrap(err)
-
-			default:
-				return 0, errors.
-					ErrInternal.
-					New("internal error").
-					Wrap(err)
 			}
 		}
 
+		return 0, errors.
+			ErrInternal.
+			New("internal error").
+			Wrap(err)
 	}
 
 	return userID, nil
 }
 
+func (r Repository) ClaimPending(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]dto.User, error) {
+
+	// Строки, захваченные другим обработчиком, пропускаются, а аренда
+	// возвращает строку в очередь, если обработчик не завершил работу
+	pending := sq.
+		Select("id").
+		From("users").
+		Where("enrichment_next_attempt_at <= NOW()").
+		OrderBy("enrichment_next_attempt_at").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	query, args, err := sq.
+		Update("users").
+		Set(
+			"enrichment_next_attempt_at",
+			sq.Expr("NOW() + make_interval(secs => ?)", lease.Seconds()),
+		).
+		Set("enrichment_status", dto.EnrichmentProcessing).
+		Set("enrichment_lease", sq.Expr("enrichment_lease + 1")).
+		Where(sq.Expr("id IN (?)", pending)).
+		Suffix(
+			"RETURNING id, name, surname, patronymic, country_hint, " +
+				"enrichment_attempts, enrichment_lease",
+		).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+
+	logger := r.logger.WithFields(map[string]any{
+		"request": map[string]any{
+			"query": query,
+			"args": map[string]any{
+				"limit": limit,
+				"lease": lease.String(),
+			},
+		},
+	})
+
+	if err != nil {
+		logger.Warnf("error on create sql query: %s", err)
+
+		return []dto.User{}, err
+	}
+
+	users := make([]dto.User, 0)
+
+	if err := r.db.SelectContext(ctx, &users, query, args...); err != nil {
+		logger.Warnf("error on claim pending users: %s", err)
+
+		return []dto.User{}, errors.
+			ErrInternal.
+			New("error on claim pending users").
+			Wrap(err)
+	}
+
+	return users, nil
+}
+
+// CompleteEnrichment записывает результат, только если аренда
+// по-прежнему принадлежит обработчику, и не трогает атрибуты,
+// заданные вручную во время обогащения
+func (r Repository) CompleteEnrichment(
+	ctx context.Context,
+	id int,
+	lease int,
+	enrichment dto.EnrichmentDTO,
+) error {
+
+	columns := map[string]any{
+		"enrichment_status":          dto.EnrichmentDone,
+		"enrichment_error":           nil,
+		"enrichment_next_attempt_at": nil,
+	}
+
+	keepManual(
+		columns,
+		enrichmentColumns(enrichment),
+		[]string{"age", "gender", "country"},
+	)
+
+	query, args, err := sq.
+		Update("users").
+		SetMap(columns).
+		Where(leased(id, lease)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+
+	logger := r.logger.WithFields(map[string]any{
+		"request": map[string]any{
+			"query": query,
+			"args": map[string]any{
+				"id":    id,
+				"lease": lease,
+			},
+		},
+	})
+
+	if err != nil {
+		logger.Warnf("error on create sql query: %s", err)
+
+		return err
+	}
+
+	logger.Info(query)
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.Warnf("error on complete enrichment: %s", err)
+
+		if e, ok := err.(*pq.Error); ok && e.Code == pgerr.UniqueViolation {
+			return errors.
+				ErrAlreadyExists.
+				New("user already exists").
+				Wrap(err)
+		}
+
+		return errors.
+			ErrInternal.
+			New("error on complete enrichment").
+			Wrap(err)
+	}
+
+	return leaseResult(result)
+}
+
+func (r Repository) FailEnrichment(
+	ctx context.Context,
+	id int,
+	lease int,
+	reason string,
+	nextAttemptAt *time.Time,
+) error {
+
+	query, args, err := sq.
+		Update("users").
+		SetMap(map[string]any{
+			"enrichment_status":          dto.EnrichmentFailed,
+			"enrichment_attempts":        sq.Expr("enrichment_attempts + 1"),
+			"enrichment_error":           reason,
+			"enrichment_next_attempt_at": nextAttemptAt,
+		}).
+		Where(leased(id, lease)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+
+	logger := r.logger.WithFields(map[string]any{
+		"request": map[string]any{
+			"query": query,
+			"args": map[string]any{
+				"id":              id,
+				"lease":           lease,
+				"reason":          reason,
+				"next_attempt_at": nextAttemptAt,
+			},
+		},
+	})
+
+	if err != nil {
+		logger.Warnf("error on create sql query: %s", err)
+
+		return err
+	}
+
+	logger.Info(query)
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.Warnf("error on fail enrichment: %s", err)
+
+		return errors.
+			ErrInternal.
+			New("error on fail enrichment").
+			Wrap(err)
+	}
+
+	return leaseResult(result)
+}
+
+// leased отбирает пользователя, если его аренда не истекла
+// и не перешла к другому обработчику
+func leased(
+	id int,
+	lease int,
+) sq.Eq {
+
+	return sq.Eq{
+		"id":                id,
+		"enrichment_status": dto.EnrichmentProcessing,
+		"enrichment_lease":  lease,
+	}
+}
+
+func leaseResult(
+	result sql.Result,
+) error {
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.
+			ErrInternal.
+			New("can't get affected rows").
+			Wrap(err)
+	}
+
+	if affected == 0 {
+		return errors.
+			ErrLeaseLost.
+			New("enrichment lease is lost")
+	}
+
+	return nil
+}
+
+// UpdateEnrichment перезаписывает столбцы перечисленных атрибутов,
+// кроме заданных вручную, и отмечает обогащение завершённым
+func (r Repository) UpdateEnrichment(
+	ctx context.Context,
+	id int,
+	enrichment dto.EnrichmentDTO,
+	attributes []string,
+) error {
+
+	values := enrichmentColumns(enrichment)
+	columns := map[string]any{
+		"enrichment_status":          dto.EnrichmentDone,
+		"enrichment_error":           nil,
+		"enrichment_next_attempt_at": nil,
+	}
+
+	keepManual(columns, values, attributes)
+
+	query, args, err := sq.
+		Update("users").
+		SetMap(columns).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+
+	logger := r.logger.WithFields(map[string]any{
+		"request": map[string]any{
+			"query": query,
+			"args": map[string]any{
+				"id":         id,
+				"attributes": attributes,
+			},
+		},
+	})
+
+	if err != nil {
+		logger.Warnf("error on create sql query: %s", err)
+
+		return err
+	}
+
+	logger.Info(query)
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		logger.Warnf("error on update enrichment: %s", err)
+
+		return errors.
+			ErrInternal.
+			New("error on update enrichment").
+			Wrap(err)
+	}
+
+	return nil
+}
+
+// keepManual добавляет в columns значения атрибутов. Источник
+// проверяется в самом запросе: значение могли изменить вручную
+// уже после чтения пользователя
+func keepManual(
+	columns map[string]any,
+	values map[string]any,
+	attributes []string,
+) {
+
+	for _, attribute := range attributes {
+		guard := fmt.Sprintf("CASE WHEN %s_source = ? THEN %%s ELSE ? END", attribute)
+
+		for _, column := range enrichmentAttributeColumns[attribute] {
+			columns[column] = sq.Expr(
+				fmt.Sprintf(guard, column),
+				dto.SourceManual,
+				values[column],
+			)
+		}
+	}
+}
+
+var enrichmentAttributeColumns = map[string][]string{
+	"age":     {"age", "age_count", "age_source"},
+	"gender":  {"gender", "gender_probability", "gender_count", "gender_source"},
+	"country": {"country", "country_probability", "country_count", "countries", "country_source"},
+}
+
+// enrichmentColumns возвращает все обогащаемые столбцы,
+// незаполненные атрибуты сбрасываются в NULL
+func enrichmentColumns(
+	enrichment dto.EnrichmentDTO,
+) map[string]any {
+
+	columns := map[string]any{
+		"age":                 nil,
+		"age_count":           nil,
+		"gender":              nil,
+		"gender_probability":  nil,
+		"gender_count":        nil,
+		"country":             nil,
+		"country_probability": nil,
+		"country_count":       nil,
+		"countries":           nil,
+		"age_source":          nil,
+		"gender_source":       nil,
+		"country_source":      nil,
+	}
+
+	if age := enrichment.Age; age != nil {
+		columns["age"] = age.Age
+		columns["age_count"] = age.Count
+		columns["age_source"] = age.Source
+	}
+
+	if gender := enrichment.Gender; gender != nil {
+		columns["gender"] = gender.Gender
+		columns["gender_probability"] = gender.Probability
+		columns["gender_count"] = gender.Count
+		columns["gender_source"] = gender.Source
+	}
+
+	if country := enrichment.Country; country != nil {
+		columns["country"] = country.Country
+		columns["country_probability"] = country.Probability
+		columns["country_count"] = country.Count
+		columns["countries"] = country.Countries
+		columns["country_source"] = country.Source
+	}
+
+	return columns
+}
+
+var userColumns = []string{
+	"id",
+	"name", "surname", "patronymic",
+	"age", "gender", "country",
+	"age_count",
+	"gender_probability", "gender_count",
+	"country_probability", "country_count", "countries",
+	"age_source", "gender_source", "country_source",
+	"country_hint",
+	"enrichment_status", "enrichment_error",
+}
+
 func (r Repository) Get(
 	ctx context.Context,
 	get dto.GetDTO,
 	filter dto.FilterDTO,
 	sort dto.SortDTO,
-) ([]dto.User, error) {
+) (dto.UserList, error) {
+
+	spec, err := newSortSpec(sort)
+	if err != nil {
+		return dto.UserList{}, err
+	}
 
 	sb := sq.
-		Select(
-			"id",
-			"name", "surname", "patronymic",
-			"age", "gender", "country",
-		).
+		Select(userColumns...).
 		From("users").
-		OrderBy(
-			fmt.Sprintf("%s %s", sort.SortBy, sort.SortOrder),
-		).
 		Offset(uint64(get.Offset)).
 		Limit(uint64(get.Limit)).
 		PlaceholderFormat(sq.Dollar)
 
-	sb = r.where(sb, filter)
+	sb, err = r.where(sb, filter)
+	if err != nil {
+		return dto.UserList{}, err
+	}
+
+	// Похожие имена идут по убыванию сходства, sort
+	// упорядочивает равные по сходству
+	if rank := similarityRank(filter); rank != nil {
+		sb = sb.OrderByClause(rank)
+	}
+
+	sb = sb.OrderBy(spec.orderBy(false)...)
 
 	query, args, err := sb.ToSql()
 
@@ -143,7 +510,7 @@ func (r Repository) Get(
 	if err != nil {
 		logger.Warnf("error on create sql query: %s", err)
 
-		return []dto.User{}, err
+		return dto.UserList{}, err
 	}
 
 	logger.Info(query)
@@ -154,19 +521,166 @@ func (r Repository) Get(
 		logger.Warnf("error on get users: %s", err)
 
 		if !errpkg.Is(err, sql.ErrNoRows) {
-			return []dto.User{}, errors.
+			return dto.UserList{}, errors.
 				ErrInternal.
 				New("error on get users").
 				Wrap(err)
 		}
 
-		return []dto.User{}, errors.
+		return dto.UserList{}, errors.
 			ErrNotFound.
 			New("haven't users").
 			Wrap(err)
 	}
 
-	return users, nil
+	list := dto.UserList{
+		Items:  users,
+		Limit:  get.Limit,
+		Offset: get.Offset,
+	}
+
+	if get.Count == "" || get.Count == dto.CountNone {
+		return list, nil
+	}
+
+	total, estimated, err := r.count(ctx, filter, get.Count)
+	if err != nil {
+		return dto.UserList{}, err
+	}
+
+	list.Total = &total
+	list.Estimated = estimated
+
+	return list, nil
+}
+
+// GetPage выбирает страницу по курсору: в отличие от OFFSET, запрос
+// не перебирает пропущенные строки, а вставка новых не сдвигает страницы
+func (r Repository) GetPage(
+	ctx context.Context,
+	page dto.PageDTO,
+	filter dto.FilterDTO,
+	sort dto.SortDTO,
+) (dto.UserPage, error) {
+
+	// Ранжирование по сходству несовместимо с порядком курсора:
+	// строки с разным сходством оказались бы на чужих страницах
+	if similarityRank(filter) != nil {
+		return dto.UserPage{}, errors.
+			ErrInvalidValue.
+			New(fmt.Sprintf("operator %q can't be combined with cursor pagination", dto.OpSimilar))
+	}
+
+	spec, err := newSortSpec(sort)
+	if err != nil {
+		return dto.UserPage{}, err
+	}
+
+	backward := page.Backward
+
+	sb := sq.
+		Select(userColumns...).
+		From("users").
+		PlaceholderFormat(sq.Dollar)
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return dto.UserPage{}, err
+		}
+
+		backward = backward || c.Backward
+
+		after, err := spec.after(c, backward)
+		if err != nil {
+			return dto.UserPage{}, err
+		}
+
+		sb = sb.Where(after)
+	}
+
+	// Лишняя строка показывает, есть ли следующая страница
+	sb, err = r.where(sb, filter)
+	if err != nil {
+		return dto.UserPage{}, err
+	}
+
+	sb = sb.
+		OrderBy(spec.orderBy(backward)...).
+		Limit(uint64(page.Limit + 1))
+
+	query, args, err := sb.ToSql()
+
+	logger := r.logger.WithFields(map[string]any{
+		"request": map[string]any{
+			"query": query,
+			"args": map[string]any{
+				"limit":    page.Limit,
+				"backward": backward,
+			},
+		},
+	})
+
+	if err != nil {
+		logger.Warnf("error on create sql query: %s", err)
+
+		return dto.UserPage{}, err
+	}
+
+	logger.Info(query)
+
+	users := make([]dto.User, 0)
+
+	if err := r.db.SelectContext(ctx, &users, query, args...); err != nil {
+		logger.Warnf("error on get users page: %s", err)
+
+		return dto.UserPage{}, errors.
+			ErrInternal.
+			New("error on get users page").
+			Wrap(err)
+	}
+
+	more := len(users) > page.Limit
+	if more {
+		users = users[:page.Limit]
+	}
+
+	if backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+
+	result := dto.UserPage{
+		Items:   users,
+		Cursors: make([]string, len(users)),
+	}
+
+	for i, user := range users {
+		result.Cursors[i] = encodeCursor(spec.cursor(user, false))
+	}
+
+	if len(users) == 0 {
+		return result, nil
+	}
+
+	// Со стороны курсора строки есть: с неё пришли на эту страницу
+	hasNext, hasPrev := more, page.Cursor != ""
+	if backward {
+		hasNext, hasPrev = page.Cursor != "", more
+	}
+
+	if hasNext {
+		next := encodeCursor(spec.cursor(users[len(users)-1], false))
+		result.NextCursor = &next
+	}
+
+	if hasPrev {
+		prev := encodeCursor(spec.cursor(users[0], true))
+		result.PrevCursor = &prev
+	}
+
+	return result, nil
 }
 
 func (r Repository) GetById(
@@ -175,11 +689,7 @@ func (r Repository) GetById(
 ) (dto.User, error) {
 
 	query, args, err := sq.
-		Select(
-			"id",
-			"name", "surname", "patronymic",
-			"age", "gender", "country",
-		).
+		Select(userColumns...).
 		From("users").
 		OrderBy("id").
 		Where(sq.Eq{"id": id}).
@@ -238,6 +748,12 @@ func (r Repository) Update(
 			"age":        data.Age,
 			"gender":     data.Gender,
 			"country":    data.Country,
+
+			// Вручную заданы только изменившиеся значения,
+			// остальные сохраняют прежний источник
+			"age_source":     manualIfChanged("age", data.Age),
+			"gender_source":  manualIfChanged("gender", data.Gender),
+			"country_source": manualIfChanged("country", data.Country),
 		}).
 		Where(sq.Eq{"id": data.ID}).
 		Suffix("RETURNING id").
@@ -306,6 +822,20 @@ func (r Repository) Update(
 	return userID, err
 }
 
+func manualIfChanged(
+	attribute string,
+	value any,
+) sq.Sqlizer {
+
+	return sq.Expr(
+		fmt.Sprintf(
+			"CASE WHEN %s IS DISTINCT FROM ? THEN '%s' ELSE %s_source END",
+			attribute, dto.SourceManual, attribute,
+		),
+		value,
+	)
+}
+
 func (r Repository) Delete(
 	ctx context.Context,
 	id int,
@@ -0,0 +1,107 @@
+package enrichment
+
+import (
+	"context"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"net/http"
+)
+
+type agify struct {
+	remote remote
+	logger log.Logger
+}
+
+type agifyResponse struct {
+	Count int    `json:"count"`
+	Age   *int   `json:"age"`
+	Name  string `json:"name"`
+}
+
+func newAgify(
+	config config.EnrichmentProvider,
+	client *http.Client,
+	logger log.Logger,
+) (Provider[dto.AgeDTO], error) {
+
+	logger = logger.WithField("provider", "agify")
+
+	return agify{
+		remote: newRemote("agify", config, client, logger),
+		logger: logger,
+	}, nil
+}
+
+func (a agify) Lookup(
+	ctx context.Context,
+	name string,
+	countryID string,
+) (dto.AgeDTO, error) {
+
+	var data agifyResponse
+
+	if err := a.remote.get(ctx, name, countryID, &data); err != nil {
+		a.logger.WithField("name", name).Warnf("can't get from agify: %s", err)
+
+		return dto.AgeDTO{}, errors.
+			ErrCantEnrichment.
+			New("can't agify").
+			Wrap(err)
+	}
+
+	age, ok := a.parse(data)
+	if !ok {
+		a.logger.WithField("name", name).Warn("agify doesn't know the name")
+
+		return dto.AgeDTO{}, errors.
+			ErrCantEnrichment.
+			New("can't agify").
+			Wrap(errors.ErrUnknownName.New("unknown name"))
+	}
+
+	return age, nil
+}
+
+func (a agify) LookupBatch(
+	ctx context.Context,
+	names []string,
+	countryID string,
+) (map[string]dto.AgeDTO, error) {
+
+	ages, err := remoteBatch(ctx, a.remote, names, countryID, a.parse)
+	if err != nil {
+		a.logger.WithField("names", names).Warnf("can't get batch from agify: %s", err)
+
+		return ages, errors.
+			ErrCantEnrichment.
+			New("can't agify").
+			Wrap(err)
+	}
+
+	return ages, nil
+}
+
+func (a agify) Health() []dto.ProviderHealth {
+	return []dto.ProviderHealth{a.remote.health()}
+}
+
+func (a agify) Quota() []dto.ProviderQuota {
+	return []dto.ProviderQuota{a.remote.quotaState()}
+}
+
+func (a agify) Exhausted() error {
+	return a.remote.quota.allow()
+}
+
+func (a agify) parse(data agifyResponse) (dto.AgeDTO, bool) {
+	if data.Age == nil {
+		return dto.AgeDTO{}, false
+	}
+
+	return dto.AgeDTO{
+		Age:   *data.Age,
+		Count: data.Count,
+	}, true
+}
@@ -0,0 +1,101 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+)
+
+// Провайдеры принимают не более 10 имён в одном запросе
+const maxBatchSize = 10
+
+type BatchProvider[T any] interface {
+	Provider[T]
+	LookupBatch(context.Context, []string, string) (map[string]T, error)
+}
+
+// lookupBatch возвращает значения по каждому известному провайдеру имени,
+// неизвестные имена в результат не попадают.
+func lookupBatch[T any](
+	ctx context.Context,
+	provider Provider[T],
+	names []string,
+	countryID string,
+) (map[string]T, error) {
+
+	if batch, ok := provider.(BatchProvider[T]); ok {
+		return batch.LookupBatch(ctx, names, countryID)
+	}
+
+	result := make(map[string]T, len(names))
+
+	for _, name := range dedupe(names) {
+		value, err := provider.Lookup(ctx, name, countryID)
+		if err != nil {
+			if errpkg.Has(err, errors.ErrUnknownName) {
+				continue
+			}
+
+			return result, err
+		}
+
+		result[name] = value
+	}
+
+	return result, nil
+}
+
+func remoteBatch[R any, T any](
+	ctx context.Context,
+	remote remote,
+	names []string,
+	countryID string,
+	parse func(R) (T, bool),
+) (map[string]T, error) {
+
+	unique := dedupe(names)
+	result := make(map[string]T, len(unique))
+
+	for start := 0; start < len(unique); start += maxBatchSize {
+		chunk := unique[start:min(start+maxBatchSize, len(unique))]
+
+		data := make([]R, 0, len(chunk))
+
+		if err := remote.getBatch(ctx, chunk, countryID, &data); err != nil {
+			return result, err
+		}
+
+		if len(data) != len(chunk) {
+			return result, fmt.Errorf(
+				"unexpected batch response length: got %d, want %d",
+				len(data), len(chunk),
+			)
+		}
+
+		// Ответ приходит в порядке переданных имён
+		for i, name := range chunk {
+			if value, ok := parse(data[i]); ok {
+				result[name] = value
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func dedupe(names []string) []string {
+	seen := make(map[string]struct{}, len(names))
+	unique := make([]string, 0, len(names))
+
+	for _, name := range names {
+		if _, ok := seen[name]; ok {
+			continue
+		}
+
+		seen[name] = struct{}{}
+		unique = append(unique, name)
+	}
+
+	return unique
+}
@@ -0,0 +1,226 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type breaker struct {
+	name             string
+	failureThreshold int
+	coolDown         time.Duration
+	halfOpenRequests int
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	successes int
+	probes    int
+	openedAt  time.Time
+
+	logger log.Logger
+}
+
+func newBreaker(
+	name string,
+	config config.EnrichmentBreaker,
+	logger log.Logger,
+) *breaker {
+
+	halfOpenRequests := config.HalfOpenRequests
+	if halfOpenRequests <= 0 {
+		halfOpenRequests = 1
+	}
+
+	return &breaker{
+		name:             name,
+		failureThreshold: config.FailureThreshold,
+		coolDown:         config.CoolDown,
+		halfOpenRequests: halfOpenRequests,
+		logger:           logger,
+	}
+}
+
+func (b *breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance()
+
+	return b.state
+}
+
+func (b *breaker) do(
+	ctx context.Context,
+	call func(context.Context) error,
+) error {
+
+	if b.failureThreshold <= 0 {
+		return call(ctx)
+	}
+
+	if err := b.allow(); err != nil {
+		return err
+	}
+
+	err := call(ctx)
+
+	b.record(ctx, err)
+
+	return err
+}
+
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance()
+
+	switch b.state {
+	case StateOpen:
+		return errors.
+			ErrCircuitOpen.
+			New(fmt.Sprintf("%s circuit is open", b.name))
+
+	case StateHalfOpen:
+		if b.probes >= b.halfOpenRequests {
+			return errors.
+				ErrCircuitOpen.
+				New(fmt.Sprintf("%s circuit is half-open", b.name))
+		}
+
+		b.probes++
+	}
+
+	return nil
+}
+
+func (b *breaker) record(
+	ctx context.Context,
+	err error,
+) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+
+	// Отмена со стороны вызывающего ничего не говорит о провайдере
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	// Как и ограничения на нашей стороне
+	if throttled(err) {
+		return
+	}
+
+	if err == nil || !isProviderFailure(err) {
+		b.success()
+
+		return
+	}
+
+	b.failure()
+}
+
+func (b *breaker) success() {
+	switch b.state {
+	case StateClosed:
+		b.failures = 0
+
+	case StateHalfOpen:
+		b.successes++
+
+		if b.successes >= b.halfOpenRequests {
+			b.transition(StateClosed)
+		}
+	}
+}
+
+func (b *breaker) failure() {
+	switch b.state {
+	case StateClosed:
+		b.failures++
+
+		if b.failures >= b.failureThreshold {
+			b.transition(StateOpen)
+		}
+
+	case StateHalfOpen:
+		b.transition(StateOpen)
+	}
+}
+
+// advance переводит разомкнутый автомат в полуоткрытое состояние
+// по истечении cool-down. Вызывается под мьютексом.
+func (b *breaker) advance() {
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.coolDown {
+		b.transition(StateHalfOpen)
+	}
+}
+
+func (b *breaker) transition(state BreakerState) {
+	b.logger.Warnf("circuit breaker state changed: %s -> %s", b.state, state)
+
+	b.state = state
+	b.failures = 0
+	b.successes = 0
+	b.probes = 0
+
+	if state == StateOpen {
+		b.openedAt = time.Now()
+	}
+}
+
+func isProviderFailure(err error) bool {
+	if throttled(err) {
+		return false
+	}
+
+	var statusErr *statusError
+
+	if !errpkg.As(err, &statusErr) {
+		return true
+	}
+
+	return statusErr.code == http.StatusTooManyRequests ||
+		statusErr.code >= http.StatusInternalServerError
+}
+
+// throttled - запрос не отправлен или прерван из-за квоты
+// или ограничения частоты, а не из-за сбоя провайдера
+func throttled(err error) bool {
+	return errpkg.Has(err, errors.ErrQuotaExhausted) ||
+		errpkg.Has(err, errors.ErrRateLimited)
+}
@@ -0,0 +1,203 @@
+package enrichment
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+const testCoolDown = 20 * time.Millisecond
+
+var (
+	errUnavailable = &statusError{code: http.StatusServiceUnavailable}
+	errNotFound    = &statusError{code: http.StatusNotFound}
+)
+
+func newTestBreaker(
+	threshold int,
+	halfOpenRequests int,
+) *breaker {
+
+	return newBreaker("test", config.EnrichmentBreaker{
+		FailureThreshold: threshold,
+		CoolDown:         testCoolDown,
+		HalfOpenRequests: halfOpenRequests,
+	}, log.NewLogrusLogger())
+}
+
+func callBreaker(
+	b *breaker,
+	err error,
+) error {
+
+	return b.do(context.Background(), func(context.Context) error {
+		return err
+	})
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b := newTestBreaker(3, 1)
+
+	callBreaker(b, errUnavailable)
+	callBreaker(b, errUnavailable)
+
+	// Успех сбрасывает счётчик ошибок подряд
+	callBreaker(b, nil)
+
+	callBreaker(b, errUnavailable)
+	callBreaker(b, errUnavailable)
+
+	if state := b.State(); state != StateClosed {
+		t.Fatalf("state = %s, want %s", state, StateClosed)
+	}
+
+	callBreaker(b, errUnavailable)
+
+	if state := b.State(); state != StateOpen {
+		t.Fatalf("state = %s, want %s", state, StateOpen)
+	}
+
+	called := false
+
+	err := b.do(context.Background(), func(context.Context) error {
+		called = true
+
+		return nil
+	})
+
+	if called {
+		t.Error("open breaker called the provider")
+	}
+
+	if !errpkg.Has(err, errors.ErrCircuitOpen) {
+		t.Errorf("error = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestBreakerIgnoresNonProviderFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "client error", err: errNotFound},
+		{name: "rate limit", err: errors.ErrRateLimited.New("rate limit exceeded")},
+		{name: "quota", err: errors.ErrQuotaExhausted.New("quota exhausted")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBreaker(1, 1)
+
+			callBreaker(b, tt.err)
+
+			if state := b.State(); state != StateClosed {
+				t.Errorf("state = %s, want %s", state, StateClosed)
+			}
+		})
+	}
+}
+
+func TestBreakerIgnoresCallerCancellation(t *testing.T) {
+	b := newTestBreaker(1, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	b.do(ctx, func(ctx context.Context) error {
+		return ctx.Err()
+	})
+
+	if state := b.State(); state != StateClosed {
+		t.Errorf("state = %s, want %s", state, StateClosed)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name   string
+		probes []error
+		want   BreakerState
+	}{
+		{
+			name:   "successful probes close",
+			probes: []error{nil, nil},
+			want:   StateClosed,
+		},
+		{
+			name:   "failed probe reopens",
+			probes: []error{errUnavailable},
+			want:   StateOpen,
+		},
+		{
+			name:   "all probes must succeed",
+			probes: []error{nil, errUnavailable},
+			want:   StateOpen,
+		},
+		{
+			name:   "client error counts as success",
+			probes: []error{errNotFound, nil},
+			want:   StateClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBreaker(1, 2)
+
+			callBreaker(b, errUnavailable)
+
+			time.Sleep(testCoolDown)
+
+			if state := b.State(); state != StateHalfOpen {
+				t.Fatalf("state = %s, want %s", state, StateHalfOpen)
+			}
+
+			for _, probe := range tt.probes {
+				if err := callBreaker(b, probe); !stderrors.Is(err, probe) {
+					t.Fatalf("probe error = %v, want %v", err, probe)
+				}
+			}
+
+			if state := b.State(); state != tt.want {
+				t.Errorf("state = %s, want %s", state, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerLimitsHalfOpenProbes(t *testing.T) {
+	b := newTestBreaker(1, 1)
+
+	callBreaker(b, errUnavailable)
+
+	time.Sleep(testCoolDown)
+
+	if err := b.allow(); err != nil {
+		t.Fatalf("first probe is rejected: %s", err)
+	}
+
+	if err := b.allow(); !errpkg.Has(err, errors.ErrCircuitOpen) {
+		t.Errorf("second probe error = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := newTestBreaker(0, 1)
+
+	for i := 0; i < 10; i++ {
+		if err := callBreaker(b, errUnavailable); err != errUnavailable {
+			t.Fatalf("call error = %v, want %v", err, errUnavailable)
+		}
+	}
+
+	if state := b.State(); state != StateClosed {
+		t.Errorf("state = %s, want %s", state, StateClosed)
+	}
+}
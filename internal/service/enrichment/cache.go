@@ -0,0 +1,350 @@
+package enrichment
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Cache interface {
+	Get(context.Context, string) ([]byte, bool, error)
+	Set(context.Context, string, []byte, time.Duration) error
+}
+
+type cacheEntry[T any] struct {
+	Value   T    `json:"value"`
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+type cached[T any] struct {
+	attribute   string
+	provider    Provider[T]
+	cache       Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	hits   *atomic.Uint64
+	misses *atomic.Uint64
+
+	logger log.Logger
+}
+
+func withCache[T any](
+	attribute string,
+	provider Provider[T],
+	cache Cache,
+	config config.EnrichmentCache,
+	logger log.Logger,
+) Provider[T] {
+
+	if cache == nil {
+		return provider
+	}
+
+	return cached[T]{
+		attribute:   attribute,
+		provider:    provider,
+		cache:       cache,
+		ttl:         config.TTL,
+		negativeTTL: config.NegativeTTL,
+		hits:        &atomic.Uint64{},
+		misses:      &atomic.Uint64{},
+		logger:      logger.WithField("cache", attribute),
+	}
+}
+
+func (c cached[T]) Lookup(
+	ctx context.Context,
+	name string,
+	countryID string,
+) (T, error) {
+
+	key := c.key(name, countryID)
+	logger := c.logger.WithField("key", key)
+
+	if !bypassesCache(ctx) {
+		if value, ok := c.get(ctx, key, logger); ok {
+			c.hits.Add(1)
+
+			if value.Unknown {
+				var zero T
+
+				return zero, errors.
+					ErrCantEnrichment.
+					New(fmt.Sprintf("can't enrich %s", c.attribute)).
+					Wrap(errors.ErrUnknownName.New("unknown name (cached)"))
+			}
+
+			return value.Value, nil
+		}
+
+		c.misses.Add(1)
+	}
+
+	value, err := c.provider.Lookup(ctx, name, countryID)
+
+	switch {
+	case err == nil:
+		c.set(ctx, key, cacheEntry[T]{Value: value}, c.ttl, logger)
+
+	case errpkg.Has(err, errors.ErrUnknownName):
+		c.set(ctx, key, cacheEntry[T]{Unknown: true}, c.negativeTTL, logger)
+	}
+
+	return value, err
+}
+
+func (c cached[T]) LookupBatch(
+	ctx context.Context,
+	names []string,
+	countryID string,
+) (map[string]T, error) {
+
+	result := make(map[string]T, len(names))
+	misses := make([]string, 0, len(names))
+
+	for _, name := range dedupe(names) {
+		key := c.key(name, countryID)
+
+		if bypassesCache(ctx) {
+			misses = append(misses, name)
+
+			continue
+		}
+
+		value, ok := c.get(ctx, key, c.logger.WithField("key", key))
+		if !ok {
+			c.misses.Add(1)
+			misses = append(misses, name)
+
+			continue
+		}
+
+		c.hits.Add(1)
+
+		if !value.Unknown {
+			result[name] = value.Value
+		}
+	}
+
+	if len(misses) == 0 {
+		return result, nil
+	}
+
+	values, err := lookupBatch(ctx, c.provider, misses, countryID)
+
+	for name, value := range values {
+		result[name] = value
+
+		key := c.key(name, countryID)
+		c.set(ctx, key, cacheEntry[T]{Value: value}, c.ttl, c.logger.WithField("key", key))
+	}
+
+	if err != nil {
+		return result, err
+	}
+
+	for _, name := range misses {
+		if _, ok := values[name]; ok {
+			continue
+		}
+
+		key := c.key(name, countryID)
+		c.set(ctx, key, cacheEntry[T]{Unknown: true}, c.negativeTTL, c.logger.WithField("key", key))
+	}
+
+	return result, nil
+}
+
+type bypassCacheKey struct{}
+
+func bypassesCache(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassCacheKey{}).(bool)
+
+	return bypass
+}
+
+func (c cached[T]) Health() []dto.ProviderHealth {
+	return healthOf(c.provider)
+}
+
+func (c cached[T]) Quota() []dto.ProviderQuota {
+	return quotaOf(c.provider)
+}
+
+func (c cached[T]) Exhausted() error {
+	return exhaustedOf(c.provider)
+}
+
+func (c cached[T]) CoalescingStats() []dto.CoalescingStats {
+	return coalescingStatsOf(c.provider)
+}
+
+func (c cached[T]) CacheStats() []dto.CacheStats {
+	return []dto.CacheStats{
+		{
+			Hits:   c.hits.Load(),
+			Misses: c.misses.Load(),
+		},
+	}
+}
+
+func (c cached[T]) get(
+	ctx context.Context,
+	key string,
+	logger log.Logger,
+) (cacheEntry[T], bool) {
+
+	var entry cacheEntry[T]
+
+	data, ok, err := c.cache.Get(ctx, key)
+	if err != nil {
+		logger.Warnf("can't get from cache: %s", err)
+
+		return entry, false
+	}
+
+	if !ok {
+		return entry, false
+	}
+
+	if err := json.Unmarshal(data, &entry); err != nil {
+		logger.Warnf("can't decode cache entry: %s", err)
+
+		return entry, false
+	}
+
+	return entry, true
+}
+
+func (c cached[T]) set(
+	ctx context.Context,
+	key string,
+	entry cacheEntry[T],
+	ttl time.Duration,
+	logger log.Logger,
+) {
+
+	if ttl <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		logger.Warnf("can't encode cache entry: %s", err)
+
+		return
+	}
+
+	if err := c.cache.Set(ctx, key, data, ttl); err != nil {
+		logger.Warnf("can't set to cache: %s", err)
+	}
+}
+
+// key учитывает страну: ответ провайдера для неё может отличаться
+func (c cached[T]) key(name string, countryID string) string {
+	key := fmt.Sprintf("%s:%s", c.attribute, strings.ToLower(strings.TrimSpace(name)))
+
+	if countryID != "" {
+		key = fmt.Sprintf("%s:%s", key, strings.ToUpper(countryID))
+	}
+
+	return key
+}
+
+type memoryCache struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type memoryCacheItem struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewMemoryCache(size int) Cache {
+	return &memoryCache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (m *memoryCache) Get(
+	_ context.Context,
+	key string,
+) ([]byte, bool, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	item := element.Value.(*memoryCacheItem)
+
+	if time.Now().After(item.expiresAt) {
+		m.order.Remove(element)
+		delete(m.entries, key)
+
+		return nil, false, nil
+	}
+
+	m.order.MoveToFront(element)
+
+	return item.value, true, nil
+}
+
+func (m *memoryCache) Set(
+	_ context.Context,
+	key string,
+	value []byte,
+	ttl time.Duration,
+) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+
+	if element, ok := m.entries[key]; ok {
+		item := element.Value.(*memoryCacheItem)
+		item.value = value
+		item.expiresAt = expiresAt
+
+		m.order.MoveToFront(element)
+
+		return nil
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryCacheItem{
+		key:       key,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+
+	for m.size > 0 && m.order.Len() > m.size {
+		oldest := m.order.Back()
+
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryCacheItem).key)
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package enrichment
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+func TestMemoryCache(t *testing.T) {
+	type op struct {
+		set string
+		get string
+		ttl time.Duration
+	}
+
+	tests := []struct {
+		name    string
+		size    int
+		ops     []op
+		present []string
+		absent  []string
+	}{
+		{
+			name:    "least recently set is evicted",
+			size:    2,
+			ops:     []op{{set: "a"}, {set: "b"}, {set: "c"}},
+			present: []string{"b", "c"},
+			absent:  []string{"a"},
+		},
+		{
+			name:    "get refreshes recency",
+			size:    2,
+			ops:     []op{{set: "a"}, {set: "b"}, {get: "a"}, {set: "c"}},
+			present: []string{"a", "c"},
+			absent:  []string{"b"},
+		},
+		{
+			name:    "overwrite refreshes recency",
+			size:    2,
+			ops:     []op{{set: "a"}, {set: "b"}, {set: "a"}, {set: "c"}},
+			present: []string{"a", "c"},
+			absent:  []string{"b"},
+		},
+		{
+			name:    "zero size is unlimited",
+			ops:     []op{{set: "a"}, {set: "b"}, {set: "c"}},
+			present: []string{"a", "b", "c"},
+		},
+		{
+			name:    "expired entry is missed",
+			size:    2,
+			ops:     []op{{set: "a", ttl: time.Nanosecond}, {set: "b"}},
+			present: []string{"b"},
+			absent:  []string{"a"},
+		},
+	}
+
+	ctx := context.Background()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewMemoryCache(tt.size)
+
+			for _, op := range tt.ops {
+				if op.get != "" {
+					cache.Get(ctx, op.get)
+
+					continue
+				}
+
+				ttl := op.ttl
+				if ttl == 0 {
+					ttl = time.Minute
+				}
+
+				if err := cache.Set(ctx, op.set, []byte(op.set), ttl); err != nil {
+					t.Fatalf("Set(%q) error: %s", op.set, err)
+				}
+			}
+
+			time.Sleep(time.Millisecond)
+
+			for _, key := range tt.present {
+				value, ok, err := cache.Get(ctx, key)
+				if err != nil || !ok || string(value) != key {
+					t.Errorf("Get(%q) = %q, %t, %v, want %q", key, value, ok, err, key)
+				}
+			}
+
+			for _, key := range tt.absent {
+				if _, ok, _ := cache.Get(ctx, key); ok {
+					t.Errorf("Get(%q) found evicted entry", key)
+				}
+			}
+		})
+	}
+}
+
+func TestCachedLookup(t *testing.T) {
+	tests := []struct {
+		name        string
+		negativeTTL time.Duration
+		bypass      bool
+		lookup      string
+		calls       int
+		hits        uint64
+		misses      uint64
+	}{
+		{
+			name:   "known name is cached",
+			lookup: "dmitriy",
+			calls:  1,
+			hits:   1,
+			misses: 1,
+		},
+		{
+			name:        "unknown name is cached",
+			negativeTTL: time.Minute,
+			lookup:      "unknown",
+			calls:       1,
+			hits:        1,
+			misses:      1,
+		},
+		{
+			name:   "unknown name without negative ttl",
+			lookup: "unknown",
+			calls:  2,
+			misses: 2,
+		},
+		{
+			name:   "bypass asks the provider",
+			bypass: true,
+			lookup: "dmitriy",
+			calls:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newProviderStub()
+			close(stub.release)
+
+			provider := withCache[int]("age", stub, NewMemoryCache(10), config.EnrichmentCache{
+				TTL:         time.Minute,
+				NegativeTTL: tt.negativeTTL,
+			}, log.NewLogrusLogger())
+
+			ctx := context.Background()
+			if tt.bypass {
+				ctx = Service{}.BypassCache(ctx)
+			}
+
+			for i := 0; i < 2; i++ {
+				value, err := provider.Lookup(ctx, tt.lookup, "")
+
+				if tt.lookup == "unknown" {
+					if !errpkg.Has(err, errors.ErrUnknownName) {
+						t.Errorf("Lookup() error = %v, want ErrUnknownName", err)
+					}
+
+					continue
+				}
+
+				if err != nil || value != len(tt.lookup) {
+					t.Errorf("Lookup() = %d, %v, want %d", value, err, len(tt.lookup))
+				}
+			}
+
+			if calls := len(stub.calls()); calls != tt.calls {
+				t.Errorf("provider calls = %d, want %d", calls, tt.calls)
+			}
+
+			stats := cacheStatsOf(provider)[0]
+
+			if stats.Hits != tt.hits || stats.Misses != tt.misses {
+				t.Errorf("stats = %+v, want %d hits and %d misses", stats, tt.hits, tt.misses)
+			}
+		})
+	}
+}
+
+func TestCachedLookupBatch(t *testing.T) {
+	stub := newProviderStub()
+	close(stub.release)
+
+	provider := withCache[int]("age", stub, NewMemoryCache(10), config.EnrichmentCache{
+		TTL:         time.Minute,
+		NegativeTTL: time.Minute,
+	}, log.NewLogrusLogger())
+
+	ctx := context.Background()
+
+	if _, err := lookupBatch(ctx, provider, []string{"ivan", "unknown"}, "RU"); err != nil {
+		t.Fatalf("LookupBatch() error: %s", err)
+	}
+
+	// Известные и неизвестные имена из пакета берутся из кеша,
+	// к провайдеру уходят только новые
+	values, err := lookupBatch(ctx, provider, []string{"ivan", "unknown", "olga"}, "RU")
+	if err != nil {
+		t.Fatalf("LookupBatch() error: %s", err)
+	}
+
+	if want := map[string]int{"ivan": 4, "olga": 4}; !reflect.DeepEqual(values, want) {
+		t.Errorf("LookupBatch() = %v, want %v", values, want)
+	}
+
+	// Ответ для другой страны кешируется отдельно
+	if _, err := provider.Lookup(ctx, "ivan", ""); err != nil {
+		t.Fatalf("Lookup() error: %s", err)
+	}
+
+	want := [][]string{{"ivan", "unknown"}, {"olga"}, {"ivan"}}
+
+	if calls := stub.calls(); !reflect.DeepEqual(calls, want) {
+		t.Errorf("provider calls = %q, want %q", calls, want)
+	}
+}
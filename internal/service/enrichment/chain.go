@@ -0,0 +1,156 @@
+package enrichment
+
+import (
+	"context"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+type chainLink[T any] struct {
+	name     string
+	provider Provider[T]
+}
+
+// chain опрашивает провайдеров по порядку, пока один из них не вернёт
+// значение, и помечает значение именем ответившего провайдера
+type chain[T any] struct {
+	links      []chainLink[T]
+	withSource func(T, string) T
+
+	logger log.Logger
+}
+
+func (c chain[T]) Lookup(
+	ctx context.Context,
+	name string,
+	countryID string,
+) (T, error) {
+
+	var (
+		zero    T
+		lastErr error
+		failure error
+	)
+
+	for _, link := range c.links {
+		value, err := link.provider.Lookup(ctx, name, countryID)
+		if err == nil {
+			return c.withSource(value, link.name), nil
+		}
+
+		if ctx.Err() != nil {
+			return zero, err
+		}
+
+		if len(c.links) > 1 {
+			c.logger.WithFields(map[string]any{
+				"name":     name,
+				"provider": link.name,
+			}).Warnf("provider failed, trying next in chain: %s", err)
+		}
+
+		lastErr = err
+
+		if failure == nil && !errpkg.Has(err, errors.ErrUnknownName) {
+			failure = err
+		}
+	}
+
+	// Сбой провайдера важнее неизвестного имени: запрос стоит повторить
+	if failure != nil {
+		return zero, failure
+	}
+
+	return zero, lastErr
+}
+
+func (c chain[T]) LookupBatch(
+	ctx context.Context,
+	names []string,
+	countryID string,
+) (map[string]T, error) {
+
+	remaining := dedupe(names)
+	result := make(map[string]T, len(remaining))
+
+	var failure error
+
+	for _, link := range c.links {
+		if len(remaining) == 0 {
+			break
+		}
+
+		values, err := lookupBatch(ctx, link.provider, remaining, countryID)
+
+		for name, value := range values {
+			result[name] = c.withSource(value, link.name)
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, err
+			}
+
+			c.logger.WithFields(map[string]any{
+				"names":    remaining,
+				"provider": link.name,
+			}).Warnf("provider failed, trying next in chain: %s", err)
+
+			if failure == nil {
+				failure = err
+			}
+		}
+
+		unresolved := remaining[:0:0]
+
+		for _, name := range remaining {
+			if _, ok := result[name]; !ok {
+				unresolved = append(unresolved, name)
+			}
+		}
+
+		remaining = unresolved
+	}
+
+	if len(remaining) > 0 && failure != nil {
+		return result, failure
+	}
+
+	return result, nil
+}
+
+func (c chain[T]) Health() []dto.ProviderHealth {
+	health := make([]dto.ProviderHealth, 0, len(c.links))
+
+	for _, link := range c.links {
+		health = append(health, healthOf(link.provider)...)
+	}
+
+	return health
+}
+
+func (c chain[T]) Quota() []dto.ProviderQuota {
+	quota := make([]dto.ProviderQuota, 0, len(c.links))
+
+	for _, link := range c.links {
+		quota = append(quota, quotaOf(link.provider)...)
+	}
+
+	return quota
+}
+
+// Exhausted возвращает ошибку, только если квота исчерпана
+// у всех провайдеров цепочки
+func (c chain[T]) Exhausted() error {
+	var err error
+
+	for _, link := range c.links {
+		if err = exhaustedOf(link.provider); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,205 @@
+package enrichment
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+// linkStub знает возраст имён из ages, а err возвращает на любой запрос
+type linkStub struct {
+	ages map[string]int
+	err  error
+}
+
+func (l linkStub) Lookup(
+	_ context.Context,
+	name string,
+	_ string,
+) (dto.AgeDTO, error) {
+
+	if l.err != nil {
+		return dto.AgeDTO{}, l.err
+	}
+
+	age, ok := l.ages[name]
+	if !ok {
+		return dto.AgeDTO{}, errors.
+			ErrCantEnrichment.
+			New("can't agify").
+			Wrap(errors.ErrUnknownName.New("unknown name"))
+	}
+
+	return dto.AgeDTO{Age: age}, nil
+}
+
+func newTestChain(
+	links ...chainLink[dto.AgeDTO],
+) chain[dto.AgeDTO] {
+
+	return chain[dto.AgeDTO]{
+		links: links,
+		withSource: func(value dto.AgeDTO, source string) dto.AgeDTO {
+			value.Source = source
+			return value
+		},
+		logger: log.NewLogrusLogger(),
+	}
+}
+
+func TestChainLookup(t *testing.T) {
+	var (
+		knows   = linkStub{ages: map[string]int{"ivan": 40}}
+		guesses = linkStub{ages: map[string]int{"ivan": 30}}
+		unknown = linkStub{}
+		broken  = linkStub{err: errors.ErrCircuitOpen.New("circuit open")}
+	)
+
+	tests := []struct {
+		name    string
+		links   []chainLink[dto.AgeDTO]
+		want    dto.AgeDTO
+		errType *errpkg.Type
+	}{
+		{
+			name:  "first link answers",
+			links: []chainLink[dto.AgeDTO]{{"agify", knows}, {"offline", guesses}},
+			want:  dto.AgeDTO{Age: 40, Source: "agify"},
+		},
+		{
+			name:  "unknown name falls back",
+			links: []chainLink[dto.AgeDTO]{{"agify", unknown}, {"offline", guesses}},
+			want:  dto.AgeDTO{Age: 30, Source: "offline"},
+		},
+		{
+			name:  "failure falls back",
+			links: []chainLink[dto.AgeDTO]{{"agify", broken}, {"offline", guesses}},
+			want:  dto.AgeDTO{Age: 30, Source: "offline"},
+		},
+		{
+			name:    "all links don't know the name",
+			links:   []chainLink[dto.AgeDTO]{{"agify", unknown}, {"offline", unknown}},
+			errType: errors.ErrUnknownName,
+		},
+		{
+			// Сбой важнее неизвестного имени, где бы он ни произошёл
+			name:    "failure outranks unknown name",
+			links:   []chainLink[dto.AgeDTO]{{"agify", unknown}, {"offline", broken}},
+			errType: errors.ErrCircuitOpen,
+		},
+		{
+			name:    "failure before unknown name",
+			links:   []chainLink[dto.AgeDTO]{{"agify", broken}, {"offline", unknown}},
+			errType: errors.ErrCircuitOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestChain(tt.links...).Lookup(context.Background(), "ivan", "")
+
+			if tt.errType != nil {
+				if !errpkg.Has(err, tt.errType) {
+					t.Errorf("Lookup() error = %v, want %v", err, tt.errType)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Lookup() error: %s", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Lookup() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainLookupBatch(t *testing.T) {
+	var (
+		first    = linkStub{ages: map[string]int{"ivan": 40}}
+		second   = linkStub{ages: map[string]int{"ivan": 30, "olga": 35}}
+		everyone = linkStub{ages: map[string]int{"ivan": 50, "olga": 50, "sergey": 50}}
+		broken   = linkStub{err: errors.ErrCircuitOpen.New("circuit open")}
+	)
+
+	tests := []struct {
+		name    string
+		links   []chainLink[dto.AgeDTO]
+		want    map[string]dto.AgeDTO
+		errType *errpkg.Type
+	}{
+		{
+			name:  "next link gets only unresolved names",
+			links: []chainLink[dto.AgeDTO]{{"agify", first}, {"offline", second}},
+			want: map[string]dto.AgeDTO{
+				"ivan": {Age: 40, Source: "agify"},
+				"olga": {Age: 35, Source: "offline"},
+			},
+		},
+		{
+			name:  "unknown names are skipped",
+			links: []chainLink[dto.AgeDTO]{{"agify", first}},
+			want: map[string]dto.AgeDTO{
+				"ivan": {Age: 40, Source: "agify"},
+			},
+		},
+		{
+			name:  "failure is forgiven when the chain resolves everything",
+			links: []chainLink[dto.AgeDTO]{{"agify", broken}, {"offline", second}, {"stub", everyone}},
+			want: map[string]dto.AgeDTO{
+				"ivan":   {Age: 30, Source: "offline"},
+				"olga":   {Age: 35, Source: "offline"},
+				"sergey": {Age: 50, Source: "stub"},
+			},
+		},
+		{
+			name:  "failure with unresolved names",
+			links: []chainLink[dto.AgeDTO]{{"agify", broken}, {"offline", first}},
+			want: map[string]dto.AgeDTO{
+				"ivan": {Age: 40, Source: "offline"},
+			},
+			errType: errors.ErrCircuitOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestChain(tt.links...).LookupBatch(
+				context.Background(), []string{"ivan", "olga", "sergey", "ivan"}, "",
+			)
+
+			if tt.errType != nil && !errpkg.Has(err, tt.errType) {
+				t.Errorf("LookupBatch() error = %v, want %v", err, tt.errType)
+			}
+
+			if tt.errType == nil && err != nil {
+				t.Errorf("LookupBatch() error: %s", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LookupBatch() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRejectsUnknownProvider(t *testing.T) {
+	registry := NewRegistry(nil)
+
+	_, err := registry.Age(config.EnrichmentProvider{
+		Chain: []string{"agify", "crystal-ball"},
+	}, log.NewLogrusLogger())
+
+	if !errpkg.Has(err, errors.ErrInvalidValue) {
+		t.Errorf("Age() error = %v, want ErrInvalidValue", err)
+	}
+}
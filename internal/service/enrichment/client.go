@@ -0,0 +1,205 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func NewHTTPClient(
+	config config.EnrichmentHTTP,
+) *http.Client {
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	transport.MaxIdleConns = config.MaxIdleConns
+	transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = config.MaxConnsPerHost
+	transport.IdleConnTimeout = config.IdleConnTimeout
+
+	return &http.Client{
+		Transport: transport,
+	}
+}
+
+type remote struct {
+	name    string
+	client  *http.Client
+	url     string
+	timeout time.Duration
+	apiKey  string
+	retry   retry
+	breaker *breaker
+	quota   *quota
+	limiter *limiter
+}
+
+func newRemote(
+	name string,
+	config config.EnrichmentProvider,
+	client *http.Client,
+	logger log.Logger,
+) remote {
+
+	return remote{
+		name:    name,
+		client:  client,
+		url:     config.URL,
+		timeout: config.Timeout,
+		apiKey:  config.APIKey.Reveal(),
+		retry:   newRetry(config.Retry),
+		breaker: newBreaker(name, config.Breaker, logger),
+		quota:   newQuota(name, logger),
+		limiter: newLimiter(name, config.RateLimit),
+	}
+}
+
+func (r remote) get(
+	ctx context.Context,
+	name string,
+	countryID string,
+	data any,
+) error {
+
+	return r.fetch(ctx, r.nameUrl(name, countryID), data)
+}
+
+func (r remote) getBatch(
+	ctx context.Context,
+	names []string,
+	countryID string,
+	data any,
+) error {
+
+	return r.fetch(ctx, r.batchUrl(names, countryID), data)
+}
+
+func (r remote) fetch(
+	ctx context.Context,
+	url string,
+	data any,
+) error {
+
+	return r.breaker.do(ctx, func(ctx context.Context) error {
+		return r.retry.do(ctx, func(ctx context.Context) error {
+			// Квота и токен проверяются перед каждой попыткой,
+			// включая повторы; отказ не считается сбоем провайдера
+			if err := r.quota.allow(); err != nil {
+				return err
+			}
+
+			if err := r.limiter.wait(ctx); err != nil {
+				return err
+			}
+
+			return r.attempt(ctx, url, data)
+		})
+	})
+}
+
+func (r remote) quotaState() dto.ProviderQuota {
+	return r.quota.state()
+}
+
+func (r remote) health() dto.ProviderHealth {
+	return dto.ProviderHealth{
+		Provider: r.name,
+		State:    r.breaker.State().String(),
+	}
+}
+
+func (r remote) attempt(
+	ctx context.Context,
+	url string,
+	data any,
+) error {
+
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		url,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return r.redact(err)
+	}
+
+	defer resp.Body.Close()
+
+	r.quota.update(resp.Header)
+
+	if resp.StatusCode != http.StatusOK {
+		// Вычитываем тело, чтобы соединение вернулось в пул
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+		return newStatusError(resp)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(data)
+}
+
+func (r remote) nameUrl(name string, countryID string) string {
+	return r.withParams(
+		fmt.Sprintf("%s?name=%s", r.url, url.QueryEscape(name)),
+		countryID,
+	)
+}
+
+func (r remote) batchUrl(names []string, countryID string) string {
+	params := make([]string, len(names))
+	for i, name := range names {
+		params[i] = fmt.Sprintf("name[]=%s", url.QueryEscape(name))
+	}
+
+	return r.withParams(
+		fmt.Sprintf("%s?%s", r.url, strings.Join(params, "&")),
+		countryID,
+	)
+}
+
+func (r remote) withParams(u string, countryID string) string {
+	if countryID != "" {
+		u = fmt.Sprintf("%s&country_id=%s", u, url.QueryEscape(countryID))
+	}
+
+	if r.apiKey != "" {
+		u = fmt.Sprintf("%s&apikey=%s", u, url.QueryEscape(r.apiKey))
+	}
+
+	return u
+}
+
+// redact убирает ключ API из адреса в ошибке http-клиента,
+// чтобы он не попал в логи
+func (r remote) redact(err error) error {
+	var urlErr *url.Error
+
+	if r.apiKey == "" || !errpkg.As(err, &urlErr) {
+		return err
+	}
+
+	urlErr.URL = strings.ReplaceAll(urlErr.URL, url.QueryEscape(r.apiKey), "******")
+
+	return urlErr
+}
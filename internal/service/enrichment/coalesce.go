@@ -0,0 +1,307 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// flight - выполняющийся запрос к провайдеру, результат которого
+// получат все присоединившиеся к нему вызовы
+type flight[T any] struct {
+	done    chan struct{}
+	value   T
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// coalesced объединяет одновременные запросы одного имени в один
+// запрос к провайдеру. Пакетный запрос присоединяется к уже
+// выполняющимся запросам своих имён, а к провайдеру отправляет
+// только остальные
+type coalesced[T any] struct {
+	provider Provider[T]
+
+	// Ограничение времени общего запроса, 0 - без ограничения
+	timeout time.Duration
+
+	mu      *sync.Mutex
+	flights map[string]*flight[T]
+
+	calls     *atomic.Uint64
+	coalesced *atomic.Uint64
+}
+
+func withCoalescing[T any](
+	provider Provider[T],
+	timeout time.Duration,
+) Provider[T] {
+
+	return coalesced[T]{
+		provider:  provider,
+		timeout:   timeout,
+		mu:        &sync.Mutex{},
+		flights:   map[string]*flight[T]{},
+		calls:     &atomic.Uint64{},
+		coalesced: &atomic.Uint64{},
+	}
+}
+
+// Lookup ожидает общий результат, пока позволяет собственный контекст
+// вызова. Запрос к провайдеру не наследует ни отмену, ни дедлайн
+// первого вызова: он ограничен только таймаутом провайдера и
+// отменяется, когда его перестали ждать все вызовы
+func (c coalesced[T]) Lookup(
+	ctx context.Context,
+	name string,
+	countryID string,
+) (T, error) {
+
+	key := flightKey(name, countryID)
+
+	c.mu.Lock()
+
+	if f, ok := c.flights[key]; ok {
+		f.waiters++
+		c.mu.Unlock()
+
+		c.coalesced.Add(1)
+
+		return c.wait(ctx, key, f)
+	}
+
+	flightCtx, cancel := c.flightContext()
+
+	f := &flight[T]{
+		done:    make(chan struct{}),
+		waiters: 1,
+		cancel:  cancel,
+	}
+
+	c.flights[key] = f
+	c.mu.Unlock()
+
+	c.calls.Add(1)
+
+	go func() {
+		defer cancel()
+
+		f.value, f.err = c.provider.Lookup(flightCtx, name, countryID)
+
+		c.forget(key, f)
+		close(f.done)
+	}()
+
+	return c.wait(ctx, key, f)
+}
+
+func (c coalesced[T]) LookupBatch(
+	ctx context.Context,
+	names []string,
+	countryID string,
+) (map[string]T, error) {
+
+	flights := make(map[string]*flight[T], len(names))
+	started := make(map[string]*flight[T], len(names))
+
+	flightCtx, cancel := c.flightContext()
+
+	// Запрос отменяется, когда перестали ждать все его имена
+	pending := &atomic.Int64{}
+	release := func() {
+		if pending.Add(-1) == 0 {
+			cancel()
+		}
+	}
+
+	c.mu.Lock()
+
+	for _, name := range dedupe(names) {
+		key := flightKey(name, countryID)
+
+		if f, ok := c.flights[key]; ok {
+			f.waiters++
+			flights[name] = f
+
+			c.coalesced.Add(1)
+
+			continue
+		}
+
+		f := &flight[T]{
+			done:    make(chan struct{}),
+			waiters: 1,
+			cancel:  release,
+		}
+
+		c.flights[key] = f
+		flights[name] = f
+		started[name] = f
+	}
+
+	pending.Store(int64(len(started)))
+
+	c.mu.Unlock()
+
+	if len(started) == 0 {
+		cancel()
+	} else {
+		c.calls.Add(1)
+
+		go c.flyBatch(flightCtx, cancel, started, countryID)
+	}
+
+	// Первая ошибка отпускает ожидание остальных имён
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	result := make(map[string]T, len(flights))
+
+	var batchErr error
+
+	for name, f := range flights {
+		value, err := c.wait(ctx, flightKey(name, countryID), f)
+		if err != nil {
+			if errpkg.Has(err, errors.ErrUnknownName) {
+				continue
+			}
+
+			if batchErr == nil {
+				batchErr = err
+				stop()
+			}
+
+			continue
+		}
+
+		result[name] = value
+	}
+
+	return result, batchErr
+}
+
+func (c coalesced[T]) flyBatch(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	flights map[string]*flight[T],
+	countryID string,
+) {
+
+	defer cancel()
+
+	names := make([]string, 0, len(flights))
+
+	for name := range flights {
+		names = append(names, name)
+	}
+
+	values, err := lookupBatch(ctx, c.provider, names, countryID)
+
+	for name, f := range flights {
+		value, ok := values[name]
+
+		switch {
+		case err != nil:
+			f.err = err
+
+		case !ok:
+			f.err = errors.
+				ErrCantEnrichment.
+				New("can't enrich").
+				Wrap(errors.ErrUnknownName.New("unknown name"))
+
+		default:
+			f.value = value
+		}
+
+		c.forget(flightKey(name, countryID), f)
+		close(f.done)
+	}
+}
+
+func (c coalesced[T]) Health() []dto.ProviderHealth {
+	return healthOf(c.provider)
+}
+
+func (c coalesced[T]) Quota() []dto.ProviderQuota {
+	return quotaOf(c.provider)
+}
+
+func (c coalesced[T]) Exhausted() error {
+	return exhaustedOf(c.provider)
+}
+
+func (c coalesced[T]) CoalescingStats() []dto.CoalescingStats {
+	return []dto.CoalescingStats{
+		{
+			Calls:     c.calls.Load(),
+			Coalesced: c.coalesced.Load(),
+		},
+	}
+}
+
+func (c coalesced[T]) wait(
+	ctx context.Context,
+	key string,
+	f *flight[T],
+) (T, error) {
+
+	select {
+	case <-f.done:
+		return f.value, f.err
+
+	case <-ctx.Done():
+		c.mu.Lock()
+
+		f.waiters--
+
+		if f.waiters == 0 {
+			f.cancel()
+
+			if c.flights[key] == f {
+				delete(c.flights, key)
+			}
+		}
+
+		c.mu.Unlock()
+
+		var zero T
+
+		return zero, ctx.Err()
+	}
+}
+
+func (c coalesced[T]) forget(
+	key string,
+	f *flight[T],
+) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.flights[key] == f {
+		delete(c.flights, key)
+	}
+}
+
+func flightKey(
+	name string,
+	countryID string,
+) string {
+
+	return fmt.Sprintf("%s:%s", name, countryID)
+}
+
+func (c coalesced[T]) flightContext() (context.Context, context.CancelFunc) {
+	if c.timeout > 0 {
+		return context.WithTimeout(context.Background(), c.timeout)
+	}
+
+	return context.WithCancel(context.Background())
+}
@@ -0,0 +1,275 @@
+package enrichment
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+)
+
+// providerStub отвечает длиной имени и держит запросы до закрытия release
+type providerStub struct {
+	release chan struct{}
+
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func newProviderStub() *providerStub {
+	return &providerStub{release: make(chan struct{})}
+}
+
+func (p *providerStub) Lookup(
+	ctx context.Context,
+	name string,
+	countryID string,
+) (int, error) {
+
+	values, err := p.LookupBatch(ctx, []string{name}, countryID)
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := values[name]
+	if !ok {
+		return 0, errors.ErrUnknownName.New("unknown name")
+	}
+
+	return value, nil
+}
+
+func (p *providerStub) LookupBatch(
+	ctx context.Context,
+	names []string,
+	_ string,
+) (map[string]int, error) {
+
+	p.mu.Lock()
+	p.batches = append(p.batches, append([]string{}, names...))
+	p.mu.Unlock()
+
+	select {
+	case <-p.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	result := make(map[string]int, len(names))
+
+	for _, name := range names {
+		if name != "unknown" {
+			result[name] = len(name)
+		}
+	}
+
+	return result, nil
+}
+
+func (p *providerStub) calls() [][]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	calls := make([][]string, 0, len(p.batches))
+
+	for _, batch := range p.batches {
+		names := append([]string{}, batch...)
+		sort.Strings(names)
+
+		calls = append(calls, names)
+	}
+
+	return calls
+}
+
+// waitFor ждёт, пока к запросам присоединятся coalesced вызовов
+func waitFor(
+	t *testing.T,
+	provider Provider[int],
+	coalesced uint64,
+) {
+
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for coalescingStatsOf(provider)[0].Coalesced < coalesced {
+		if time.Now().After(deadline) {
+			t.Fatalf("coalesced = %d, want %d", coalescingStatsOf(provider)[0].Coalesced, coalesced)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCoalescedLookupBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		batches   [][]string
+		calls     [][]string
+		coalesced uint64
+		want      map[string]int
+	}{
+		{
+			name:      "same name",
+			batches:   [][]string{{"dmitriy"}, {"dmitriy"}, {"dmitriy"}, {"dmitriy"}, {"dmitriy"}},
+			calls:     [][]string{{"dmitriy"}},
+			coalesced: 4,
+			want:      map[string]int{"dmitriy": 7},
+		},
+		{
+			name:      "only new names are sent",
+			batches:   [][]string{{"dmitriy", "ivan"}, {"ivan", "olga", "olga"}},
+			calls:     [][]string{{"dmitriy", "ivan"}, {"olga"}},
+			coalesced: 1,
+			want:      map[string]int{"dmitriy": 7, "ivan": 4, "olga": 4},
+		},
+		{
+			name:      "unknown names are skipped",
+			batches:   [][]string{{"unknown", "ivan"}, {"unknown"}},
+			calls:     [][]string{{"ivan", "unknown"}},
+			coalesced: 1,
+			want:      map[string]int{"ivan": 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newProviderStub()
+			provider := withCoalescing[int](stub, time.Second)
+
+			var (
+				wg      sync.WaitGroup
+				mu      sync.Mutex
+				results = map[string]int{}
+			)
+
+			for i, batch := range tt.batches {
+				wg.Add(1)
+
+				go func(batch []string) {
+					defer wg.Done()
+
+					values, err := lookupBatch(context.Background(), provider, batch, "")
+					if err != nil {
+						t.Errorf("LookupBatch() error: %s", err)
+					}
+
+					mu.Lock()
+					defer mu.Unlock()
+
+					for name, value := range values {
+						results[name] = value
+					}
+				}(batch)
+
+				// Первый пакет начинает запрос, остальные к нему присоединяются
+				if i == 0 {
+					for len(stub.calls()) == 0 {
+						time.Sleep(time.Millisecond)
+					}
+				}
+			}
+
+			waitFor(t, provider, tt.coalesced)
+			close(stub.release)
+			wg.Wait()
+
+			if calls := stub.calls(); !reflect.DeepEqual(calls, tt.calls) {
+				t.Errorf("provider calls = %q, want %q", calls, tt.calls)
+			}
+
+			if !reflect.DeepEqual(results, tt.want) {
+				t.Errorf("results = %v, want %v", results, tt.want)
+			}
+
+			stats := coalescingStatsOf(provider)[0]
+
+			if stats.Calls != uint64(len(tt.calls)) || stats.Coalesced != tt.coalesced {
+				t.Errorf("stats = %+v, want %d calls and %d coalesced", stats, len(tt.calls), tt.coalesced)
+			}
+		})
+	}
+}
+
+func TestCoalescedLookupJoinsBatch(t *testing.T) {
+	stub := newProviderStub()
+	provider := withCoalescing[int](stub, time.Second)
+
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := lookupBatch(context.Background(), provider, []string{"unknown", "ivan"}, "")
+		done <- err
+	}()
+
+	for len(stub.calls()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	unknown := make(chan error, 1)
+
+	go func() {
+		_, err := provider.Lookup(context.Background(), "unknown", "")
+		unknown <- err
+	}()
+
+	waitFor(t, provider, 1)
+	close(stub.release)
+
+	if err := <-done; err != nil {
+		t.Errorf("LookupBatch() error: %s", err)
+	}
+
+	if err := <-unknown; !errpkg.Has(err, errors.ErrUnknownName) {
+		t.Errorf("Lookup(unknown) error = %v, want ErrUnknownName", err)
+	}
+}
+
+func TestCoalescedBatchOutlivesCancelledCaller(t *testing.T) {
+	stub := newProviderStub()
+	provider := withCoalescing[int](stub, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	first := make(chan error, 1)
+
+	go func() {
+		_, err := lookupBatch(ctx, provider, []string{"dmitriy", "ivan"}, "")
+		first <- err
+	}()
+
+	for len(stub.calls()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	second := make(chan map[string]int, 1)
+
+	go func() {
+		values, _ := lookupBatch(context.Background(), provider, []string{"ivan"}, "")
+		second <- values
+	}()
+
+	waitFor(t, provider, 1)
+
+	// Первый вызов уходит, но "ivan" всё ещё ждут
+	cancel()
+
+	if err := <-first; err != context.Canceled {
+		t.Fatalf("cancelled LookupBatch() error = %v, want %v", err, context.Canceled)
+	}
+
+	close(stub.release)
+
+	if values := <-second; values["ivan"] != 4 {
+		t.Errorf("LookupBatch(ivan) = %v, want ivan: 4", values)
+	}
+
+	if calls := stub.calls(); len(calls) != 1 {
+		t.Errorf("provider calls = %q, want one", calls)
+	}
+}
@@ -1,131 +1,272 @@
 package enrichment
 
 import (
-	"encoding/json"
-	"fmt"
-	"github.com/jackvonhouse/enrichment/internal/errors"
+	"context"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
 	"github.com/jackvonhouse/enrichment/pkg/log"
-	"net/http"
-)
-
-var (
-	ErrCantAgify       = errors.ErrCantEnrichment.New("can't agify")
-	ErrCantGenderize   = errors.ErrCantEnrichment.New("can't genderize")
-	ErrCantNationalize = errors.ErrCantEnrichment.New("can't nationalize")
 )
 
 type Service struct {
+	age     AgeProvider
+	gender  GenderProvider
+	country CountryProvider
+
+	agePolicy     policy[dto.AgeDTO]
+	genderPolicy  policy[dto.GenderDTO]
+	countryPolicy policy[dto.CountryDTO]
+
+	normalizer normalizer
+
 	logger log.Logger
 }
 
 func New(
+	config config.Enrichment,
+	cache Cache,
 	logger log.Logger,
-) Service {
+) (Service, error) {
 
-	return Service{
-		logger: logger.WithField("unit", "enrichment"),
-	}
+	registry := NewRegistry(NewHTTPClient(config.HTTP))
+
+	return NewWithRegistry(registry, config, cache, logger)
 }
 
-func (s Service) Agify(name string) (int, error) {
-	logger := s.logger.WithField("name", name)
+func NewWithRegistry(
+	registry *Registry,
+	config config.Enrichment,
+	cache Cache,
+	logger log.Logger,
+) (Service, error) {
+
+	logger = logger.WithField("unit", "enrichment")
+
+	normalizer, err := newNormalizer(config.Normalize)
+	if err != nil {
+		return Service{}, err
+	}
+
+	// Имена офлайн-набора приводятся к виду запросов
+	registry.datasets.normalizer = normalizer
 
-	resp, err := http.Get(s.agifyUrl(name))
+	age, err := registry.Age(config.Age, logger)
 	if err != nil {
-		logger.Warnf("can't get from agify: %s", err)
+		return Service{}, err
+	}
 
-		return 0, ErrCantAgify
+	gender, err := registry.Gender(config.Gender, logger)
+	if err != nil {
+		return Service{}, err
 	}
 
-	defer resp.Body.Close()
+	country, err := registry.Country(config.Country, logger)
+	if err != nil {
+		return Service{}, err
+	}
 
-	var data struct {
-		Count int    `json:"count"`
-		Age   int    `json:"age"`
-		Name  string `json:"name"`
+	agePolicy, err := newAgePolicy(config.Age.Policy)
+	if err != nil {
+		return Service{}, err
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		logger.Warnf("can't decode agify: %s", err)
+	genderPolicy, err := newGenderPolicy(config.Gender.Policy)
+	if err != nil {
+		return Service{}, err
+	}
 
-		return 0, ErrCantAgify
+	countryPolicy, err := newCountryPolicy(config.Country.Policy)
+	if err != nil {
+		return Service{}, err
 	}
 
-	return data.Age, nil
+	// Одновременные промахи кеша по одному имени объединяются
+	// в один запрос к провайдеру
+	age = withCoalescing(age, chainTimeout(config.Age))
+	gender = withCoalescing(gender, chainTimeout(config.Gender))
+	country = withCoalescing(country, chainTimeout(config.Country))
+
+	return Service{
+		age:           withCache("age", age, cache, config.Cache, logger),
+		gender:        withCache("gender", gender, cache, config.Cache, logger),
+		country:       withCache("country", country, cache, config.Cache, logger),
+		agePolicy:     agePolicy,
+		genderPolicy:  genderPolicy,
+		countryPolicy: countryPolicy,
+		normalizer:    normalizer,
+		logger:        logger,
+	}, nil
 }
 
-func (s Service) Genderize(name string) (string, error) {
-	logger := s.logger.WithField("name", name)
+// BypassCache заставляет запрашивать значения у провайдеров, минуя кеш;
+// полученные значения по-прежнему сохраняются в кеш
+func (s Service) BypassCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
 
-	resp, err := http.Get(s.genderizeUrl(name))
-	if err != nil {
-		logger.Warnf("can't get from genderize: %s", err)
+// AgifyBatch учитывает countryID, если он известен, пустая строка - без страны.
+// Имена, по которым политика требует ошибку, в результат не попадают
+func (s Service) AgifyBatch(
+	ctx context.Context,
+	names []string,
+	countryID string,
+) (map[string]*dto.AgeDTO, error) {
+
+	return normalizedBatch(
+		names,
+		s.normalizer,
+		func(keys []string) (map[string]*dto.AgeDTO, error) {
+			values, err := lookupBatch(ctx, s.age, keys, normalizeCountry(countryID))
+			if err != nil {
+				return nil, err
+			}
+
+			return s.agePolicy.applyBatch(keys, values), nil
+		},
+	)
+}
 
-		return "", ErrCantGenderize
-	}
+// GenderizeBatch учитывает countryID так же, как AgifyBatch
+func (s Service) GenderizeBatch(
+	ctx context.Context,
+	names []string,
+	countryID string,
+) (map[string]*dto.GenderDTO, error) {
+
+	return normalizedBatch(
+		names,
+		s.normalizer,
+		func(keys []string) (map[string]*dto.GenderDTO, error) {
+			values, err := lookupBatch(ctx, s.gender, keys, normalizeCountry(countryID))
+			if err != nil {
+				return nil, err
+			}
+
+			return s.genderPolicy.applyBatch(keys, values), nil
+		},
+	)
+}
 
-	defer resp.Body.Close()
+func (s Service) NationalizeBatch(
+	ctx context.Context,
+	names []string,
+) (map[string]*dto.CountryDTO, error) {
+
+	return normalizedBatch(
+		names,
+		s.normalizer,
+		func(keys []string) (map[string]*dto.CountryDTO, error) {
+			values, err := lookupBatch(ctx, s.country, keys, "")
+			if err != nil {
+				return nil, err
+			}
+
+			return s.countryPolicy.applyBatch(keys, values), nil
+		},
+	)
+}
 
-	var data struct {
-		Count       int     `json:"count"`
-		Name        string  `json:"name"`
-		Gender      string  `json:"gender"`
-		Probability float64 `json:"probability"`
+func (s Service) Health() []dto.ProviderHealth {
+	health := make([]dto.ProviderHealth, 0)
+
+	for _, p := range s.providers() {
+		for _, h := range healthOf(p.provider) {
+			h.Attribute = p.attribute
+			health = append(health, h)
+		}
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		logger.Warnf("can't decode genderize: %s", err)
+	return health
+}
 
-		return "", ErrCantGenderize
+func (s Service) CacheStats() []dto.CacheStats {
+	stats := make([]dto.CacheStats, 0)
+
+	for _, p := range s.providers() {
+		for _, c := range cacheStatsOf(p.provider) {
+			c.Attribute = p.attribute
+			stats = append(stats, c)
+		}
 	}
 
-	return data.Gender, nil
+	return stats
 }
 
-func (s Service) Nationalize(name string) (string, error) {
-	logger := s.logger.WithField("name", name)
-
-	resp, err := http.Get(s.nationalizeUrl(name))
-	if err != nil {
-		logger.Warnf("can't get from nationalize: %s", err)
+func (s Service) Quota() []dto.ProviderQuota {
+	quota := make([]dto.ProviderQuota, 0)
 
-		return "", ErrCantNationalize
+	for _, p := range s.providers() {
+		for _, q := range quotaOf(p.provider) {
+			q.Attribute = p.attribute
+			quota = append(quota, q)
+		}
 	}
 
-	defer resp.Body.Close()
+	return quota
+}
 
-	var data struct {
-		Count   int    `json:"count"`
-		Name    string `json:"name"`
-		Country []struct {
-			CountryID   string  `json:"country_id"`
-			Probability float64 `json:"probability"`
+// Exhausted возвращает ошибку, если хотя бы один атрибут
+// не может быть определён из-за исчерпанной квоты
+func (s Service) Exhausted() error {
+	for _, p := range s.providers() {
+		if err := exhaustedOf(p.provider); err != nil {
+			return err
 		}
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		logger.Warnf("can't decode nationalize: %s", err)
+	return nil
+}
+
+func (s Service) CoalescingStats() []dto.CoalescingStats {
+	stats := make([]dto.CoalescingStats, 0)
 
-		return "", ErrCantNationalize
+	for _, p := range s.providers() {
+		for _, c := range coalescingStatsOf(p.provider) {
+			c.Attribute = p.attribute
+			stats = append(stats, c)
+		}
 	}
 
-	if len(data.Country) == 0 {
-		logger.Warnf("error on nationalize: countries length is %d", len(data.Country))
+	return stats
+}
 
-		return "", ErrCantNationalize
+// normalizedBatch запрашивает значения по нормализованным именам
+// и возвращает их по исходным
+func normalizedBatch[T any](
+	names []string,
+	normalizer normalizer,
+	lookup func([]string) (map[string]*T, error),
+) (map[string]*T, error) {
+
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = normalizer.normalize(name)
 	}
 
-	return data.Country[0].CountryID, nil
-}
+	values, err := lookup(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*T, len(names))
+
+	for i, name := range names {
+		if value, ok := values[keys[i]]; ok {
+			result[name] = value
+		}
+	}
 
-func (s Service) agifyUrl(name string) string {
-	return fmt.Sprintf("https://api.agify.io?name=%s", name)
+	return result, nil
 }
 
-func (s Service) genderizeUrl(name string) string {
-	return fmt.Sprintf("https://api.genderize.io?name=%s", name)
+type attributeProvider struct {
+	attribute string
+	provider  any
 }
 
-func (s Service) nationalizeUrl(name string) string {
-	return fmt.Sprintf("https://api.nationalize.io?name=%s", name)
+func (s Service) providers() []attributeProvider {
+	return []attributeProvider{
+		{"age", s.age},
+		{"gender", s.gender},
+		{"country", s.country},
+	}
 }
@@ -0,0 +1,109 @@
+package enrichment
+
+import (
+	"context"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"net/http"
+)
+
+type genderize struct {
+	remote remote
+	logger log.Logger
+}
+
+type genderizeResponse struct {
+	Count       int     `json:"count"`
+	Name        string  `json:"name"`
+	Gender      *string `json:"gender"`
+	Probability float64 `json:"probability"`
+}
+
+func newGenderize(
+	config config.EnrichmentProvider,
+	client *http.Client,
+	logger log.Logger,
+) (Provider[dto.GenderDTO], error) {
+
+	logger = logger.WithField("provider", "genderize")
+
+	return genderize{
+		remote: newRemote("genderize", config, client, logger),
+		logger: logger,
+	}, nil
+}
+
+func (g genderize) Lookup(
+	ctx context.Context,
+	name string,
+	countryID string,
+) (dto.GenderDTO, error) {
+
+	var data genderizeResponse
+
+	if err := g.remote.get(ctx, name, countryID, &data); err != nil {
+		g.logger.WithField("name", name).Warnf("can't get from genderize: %s", err)
+
+		return dto.GenderDTO{}, errors.
+			ErrCantEnrichment.
+			New("can't genderize").
+			Wrap(err)
+	}
+
+	gender, ok := g.parse(data)
+	if !ok {
+		g.logger.WithField("name", name).Warn("genderize doesn't know the name")
+
+		return dto.GenderDTO{}, errors.
+			ErrCantEnrichment.
+			New("can't genderize").
+			Wrap(errors.ErrUnknownName.New("unknown name"))
+	}
+
+	return gender, nil
+}
+
+func (g genderize) LookupBatch(
+	ctx context.Context,
+	names []string,
+	countryID string,
+) (map[string]dto.GenderDTO, error) {
+
+	genders, err := remoteBatch(ctx, g.remote, names, countryID, g.parse)
+	if err != nil {
+		g.logger.WithField("names", names).Warnf("can't get batch from genderize: %s", err)
+
+		return genders, errors.
+			ErrCantEnrichment.
+			New("can't genderize").
+			Wrap(err)
+	}
+
+	return genders, nil
+}
+
+func (g genderize) Health() []dto.ProviderHealth {
+	return []dto.ProviderHealth{g.remote.health()}
+}
+
+func (g genderize) Quota() []dto.ProviderQuota {
+	return []dto.ProviderQuota{g.remote.quotaState()}
+}
+
+func (g genderize) Exhausted() error {
+	return g.remote.quota.allow()
+}
+
+func (g genderize) parse(data genderizeResponse) (dto.GenderDTO, bool) {
+	if data.Gender == nil {
+		return dto.GenderDTO{}, false
+	}
+
+	return dto.GenderDTO{
+		Gender:      *data.Gender,
+		Probability: data.Probability,
+		Count:       data.Count,
+	}, true
+}
@@ -0,0 +1,111 @@
+package enrichment
+
+import (
+	"context"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"net/http"
+)
+
+type nationalize struct {
+	remote remote
+	logger log.Logger
+}
+
+type nationalizeResponse struct {
+	Count   int           `json:"count"`
+	Name    string        `json:"name"`
+	Country dto.Countries `json:"country"`
+}
+
+func newNationalize(
+	config config.EnrichmentProvider,
+	client *http.Client,
+	logger log.Logger,
+) (Provider[dto.CountryDTO], error) {
+
+	logger = logger.WithField("provider", "nationalize")
+
+	return nationalize{
+		remote: newRemote("nationalize", config, client, logger),
+		logger: logger,
+	}, nil
+}
+
+func (n nationalize) Lookup(
+	ctx context.Context,
+	name string,
+	_ string,
+) (dto.CountryDTO, error) {
+
+	logger := n.logger.WithField("name", name)
+
+	var data nationalizeResponse
+
+	if err := n.remote.get(ctx, name, "", &data); err != nil {
+		logger.Warnf("can't get from nationalize: %s", err)
+
+		return dto.CountryDTO{}, errors.
+			ErrCantEnrichment.
+			New("can't nationalize").
+			Wrap(err)
+	}
+
+	country, ok := n.parse(data)
+	if !ok {
+		logger.Warnf("error on nationalize: countries length is %d", len(data.Country))
+
+		return dto.CountryDTO{}, errors.
+			ErrCantEnrichment.
+			New("can't nationalize").
+			Wrap(errors.ErrUnknownName.New("unknown name"))
+	}
+
+	return country, nil
+}
+
+func (n nationalize) LookupBatch(
+	ctx context.Context,
+	names []string,
+	_ string,
+) (map[string]dto.CountryDTO, error) {
+
+	countries, err := remoteBatch(ctx, n.remote, names, "", n.parse)
+	if err != nil {
+		n.logger.WithField("names", names).Warnf("can't get batch from nationalize: %s", err)
+
+		return countries, errors.
+			ErrCantEnrichment.
+			New("can't nationalize").
+			Wrap(err)
+	}
+
+	return countries, nil
+}
+
+func (n nationalize) Health() []dto.ProviderHealth {
+	return []dto.ProviderHealth{n.remote.health()}
+}
+
+func (n nationalize) Quota() []dto.ProviderQuota {
+	return []dto.ProviderQuota{n.remote.quotaState()}
+}
+
+func (n nationalize) Exhausted() error {
+	return n.remote.quota.allow()
+}
+
+func (n nationalize) parse(data nationalizeResponse) (dto.CountryDTO, bool) {
+	if len(data.Country) == 0 {
+		return dto.CountryDTO{}, false
+	}
+
+	return dto.CountryDTO{
+		Country:     data.Country[0].CountryID,
+		Probability: data.Country[0].Probability,
+		Count:       data.Count,
+		Countries:   data.Country,
+	}, true
+}
@@ -0,0 +1,134 @@
+package enrichment
+
+import (
+	"fmt"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+const (
+	TransliterationICAO = "icao"
+	TransliterationGOST = "gost"
+	TransliterationNone = "none"
+)
+
+// ICAO Doc 9303, используется в загранпаспортах РФ
+var icao = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu", 'я': "ia",
+	'і': "i", 'ї': "i", 'є': "ie", 'ґ': "g",
+}
+
+// ГОСТ 7.79-2000, система Б; твёрдый и мягкий знаки опускаются,
+// так как апострофы в имени мешают поиску у провайдеров
+var gost = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "j", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "x", 'ц': "cz", 'ч': "ch", 'ш': "sh", 'щ': "shh",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+}
+
+// normalizer приводит имя к виду, в котором его лучше всего знают
+// провайдеры: без лишних пробелов, в нижнем регистре, латиницей
+// и без диакритических знаков
+type normalizer struct {
+	scheme map[rune]string
+	gost   bool
+}
+
+func newNormalizer(
+	config config.EnrichmentNormalize,
+) (normalizer, error) {
+
+	n := normalizer{}
+
+	switch config.Transliteration {
+	case TransliterationICAO:
+		n.scheme = icao
+
+	case TransliterationGOST:
+		n.scheme = gost
+		n.gost = true
+
+	case TransliterationNone:
+
+	default:
+		return normalizer{}, errors.
+			ErrInvalidValue.
+			New(fmt.Sprintf("unknown transliteration %q", config.Transliteration))
+	}
+
+	return n, nil
+}
+
+func (n normalizer) normalize(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+
+	// cases.Caser хранит состояние, поэтому создаётся на каждый вызов
+	name = cases.Fold().String(name)
+
+	// Транслитерация выполняется до удаления диакритики,
+	// иначе «й» и «ё» превратились бы в «и» и «е»
+	if n.scheme != nil {
+		name = n.transliterate(name)
+	}
+
+	return stripDiacritics(name)
+}
+
+func (n normalizer) transliterate(name string) string {
+	letters := []rune(name)
+
+	var sb strings.Builder
+
+	for i, letter := range letters {
+		latin, ok := n.scheme[letter]
+		if !ok {
+			sb.WriteRune(letter)
+
+			continue
+		}
+
+		// По ГОСТ перед «и», «е», «ы», «й» буква «ц» передаётся как «c»
+		if n.gost && letter == 'ц' && i+1 < len(letters) &&
+			strings.ContainsRune("иеый", letters[i+1]) {
+
+			latin = "c"
+		}
+
+		sb.WriteString(latin)
+	}
+
+	return sb.String()
+}
+
+// normalizeCountry приводит код страны к виду ISO 3166-1 alpha-2
+func normalizeCountry(countryID string) string {
+	return strings.ToUpper(strings.TrimSpace(countryID))
+}
+
+func stripDiacritics(name string) string {
+	t := transform.Chain(
+		norm.NFD,
+		runes.Remove(runes.In(unicode.Mn)),
+		norm.NFC,
+	)
+
+	result, _, err := transform.String(t, name)
+	if err != nil {
+		return name
+	}
+
+	return result
+}
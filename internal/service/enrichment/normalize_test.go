@@ -0,0 +1,71 @@
+package enrichment
+
+import (
+	"testing"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name            string
+		transliteration string
+		value           string
+		want            string
+	}{
+		{name: "latin", transliteration: TransliterationICAO, value: "Dmitriy", want: "dmitriy"},
+		{name: "spaces", transliteration: TransliterationICAO, value: "  Anna   Maria ", want: "anna maria"},
+		{name: "diacritics", transliteration: TransliterationICAO, value: "José", want: "jose"},
+		{name: "icao", transliteration: TransliterationICAO, value: "Дмитрий", want: "dmitrii"},
+		{name: "icao yo", transliteration: TransliterationICAO, value: "Артём", want: "artem"},
+		{name: "icao kh and ts", transliteration: TransliterationICAO, value: "Харитон Цезарь", want: "khariton tsezar"},
+		{name: "icao ya and yu", transliteration: TransliterationICAO, value: "Юлия", want: "iuliia"},
+		{name: "icao ukrainian", transliteration: TransliterationICAO, value: "Євгенія", want: "ievgeniia"},
+		{name: "gost", transliteration: TransliterationGOST, value: "Дмитрий", want: "dmitrij"},
+		{name: "gost yo", transliteration: TransliterationGOST, value: "Артём", want: "artyom"},
+		{name: "gost ts before i", transliteration: TransliterationGOST, value: "Цецилия", want: "ceciliya"},
+		{name: "gost ts before a", transliteration: TransliterationGOST, value: "Цао", want: "czao"},
+		{name: "gost signs are dropped", transliteration: TransliterationGOST, value: "Ольга Объедкова", want: "olga obedkova"},
+		// Без транслитерации диакритика удаляется и у кириллицы
+		{name: "none keeps cyrillic", transliteration: TransliterationNone, value: "Дмитрий Ёлкин", want: "дмитрии елкин"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := newNormalizer(config.EnrichmentNormalize{Transliteration: tt.transliteration})
+			if err != nil {
+				t.Fatalf("newNormalizer() error: %s", err)
+			}
+
+			if got := n.normalize(tt.value); got != tt.want {
+				t.Errorf("normalize(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewNormalizerRejectsUnknownScheme(t *testing.T) {
+	_, err := newNormalizer(config.EnrichmentNormalize{Transliteration: "iso9"})
+	if !errpkg.Has(err, errors.ErrInvalidValue) {
+		t.Errorf("newNormalizer() error = %v, want ErrInvalidValue", err)
+	}
+}
+
+func TestNormalizeCountry(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "ru", want: "RU"},
+		{value: " Ua ", want: "UA"},
+		{value: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeCountry(tt.value); got != tt.want {
+			t.Errorf("normalizeCountry(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
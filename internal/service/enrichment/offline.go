@@ -0,0 +1,462 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type datasetRecord struct {
+	Name string `json:"name"`
+
+	Age      *int `json:"age"`
+	AgeCount int  `json:"age_count"`
+
+	Gender            *string `json:"gender"`
+	GenderProbability float64 `json:"gender_probability"`
+	GenderCount       int     `json:"gender_count"`
+
+	Countries    dto.Countries `json:"countries"`
+	CountryCount int           `json:"country_count"`
+}
+
+// datasets открывает каждый файл один раз, и провайдеры всех атрибутов
+// с одним файлом пользуются общим набором. Имена набора нормализуются
+// так же, как запросы к провайдерам
+type datasets struct {
+	mu     *sync.Mutex
+	byPath map[string]*dataset
+
+	normalizer normalizer
+}
+
+func newDatasets() *datasets {
+	return &datasets{
+		mu:     &sync.Mutex{},
+		byPath: map[string]*dataset{},
+	}
+}
+
+// open возвращает уже открытый набор файла; reload_interval
+// берётся у первого открывшего его провайдера
+func (d *datasets) open(
+	config config.EnrichmentOffline,
+	logger log.Logger,
+) (*dataset, error) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path := filepath.Clean(config.Dataset)
+
+	if set, ok := d.byPath[path]; ok {
+		return set, nil
+	}
+
+	set, err := newDataset(config, d.normalizer, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	d.byPath[path] = set
+
+	return set, nil
+}
+
+// dataset хранит статистику имён из локального файла и перечитывает
+// его не чаще reloadInterval, если файл изменился
+type dataset struct {
+	path           string
+	reloadInterval time.Duration
+	normalizer     normalizer
+
+	mu        *sync.RWMutex
+	records   map[string]datasetRecord
+	modTime   time.Time
+	checkedAt time.Time
+
+	logger log.Logger
+}
+
+func newDataset(
+	config config.EnrichmentOffline,
+	normalizer normalizer,
+	logger log.Logger,
+) (*dataset, error) {
+
+	if config.Dataset == "" {
+		return nil, errors.
+			ErrEmptyField.
+			New("empty offline dataset path")
+	}
+
+	d := &dataset{
+		path:           config.Dataset,
+		reloadInterval: config.ReloadInterval,
+		normalizer:     normalizer,
+		mu:             &sync.RWMutex{},
+		logger:         logger.WithField("dataset", config.Dataset),
+	}
+
+	if err := d.load(); err != nil {
+		d.logger.Warnf("can't load dataset: %s", err)
+
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (d *dataset) get(
+	name string,
+) (datasetRecord, bool) {
+
+	d.reload()
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	record, ok := d.records[d.normalizer.normalize(name)]
+
+	return record, ok
+}
+
+// reload перечитывает файл при изменении времени модификации;
+// при ошибке разбора остаются прежние данные
+func (d *dataset) reload() {
+	if d.reloadInterval <= 0 {
+		return
+	}
+
+	d.mu.Lock()
+
+	if time.Since(d.checkedAt) < d.reloadInterval {
+		d.mu.Unlock()
+
+		return
+	}
+
+	d.checkedAt = time.Now()
+	modTime := d.modTime
+
+	d.mu.Unlock()
+
+	info, err := os.Stat(d.path)
+	if err != nil {
+		d.logger.Warnf("can't stat dataset: %s", err)
+
+		return
+	}
+
+	if info.ModTime().Equal(modTime) {
+		return
+	}
+
+	if err := d.load(); err != nil {
+		d.logger.Warnf("can't reload dataset, keeping previous version: %s", err)
+
+		return
+	}
+
+	d.logger.Info("dataset reloaded")
+}
+
+func (d *dataset) load() error {
+	file, err := os.Open(d.path)
+	if err != nil {
+		return errors.
+			ErrInternal.
+			New("can't open dataset").
+			Wrap(err)
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return errors.
+			ErrInternal.
+			New("can't stat dataset").
+			Wrap(err)
+	}
+
+	var records []datasetRecord
+
+	switch ext := strings.ToLower(filepath.Ext(d.path)); ext {
+	case ".json":
+		err = json.NewDecoder(file).Decode(&records)
+
+	case ".csv":
+		records, err = readCSVDataset(file)
+
+	default:
+		return errors.
+			ErrInvalidValue.
+			New(fmt.Sprintf("unsupported dataset format %q", ext))
+	}
+
+	if err != nil {
+		return errors.
+			ErrInvalidValue.
+			New("can't parse dataset").
+			Wrap(err)
+	}
+
+	index := make(map[string]datasetRecord, len(records))
+
+	for _, record := range records {
+		index[d.normalizer.normalize(record.Name)] = record
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.records = index
+	d.modTime = info.ModTime()
+	d.checkedAt = time.Now()
+
+	return nil
+}
+
+// readCSVDataset читает файл с заголовком name, age, age_count, gender,
+// gender_probability, gender_count, countries, country_count;
+// countries задаётся как "RU:0.6;UA:0.2", пустая ячейка - нет данных
+func readCSVDataset(
+	r io.Reader,
+) ([]datasetRecord, error) {
+
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+
+	for i, column := range header {
+		columns[strings.TrimSpace(column)] = i
+	}
+
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("missing name column")
+	}
+
+	records := make([]datasetRecord, 0)
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := parseCSVRecord(columns, row)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		records = append(records, record)
+	}
+}
+
+func parseCSVRecord(
+	columns map[string]int,
+	row []string,
+) (datasetRecord, error) {
+
+	cell := func(column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(row) {
+			return ""
+		}
+
+		return strings.TrimSpace(row[i])
+	}
+
+	record := datasetRecord{Name: cell("name")}
+
+	var err error
+
+	if value := cell("age"); value != "" {
+		age, err := strconv.Atoi(value)
+		if err != nil {
+			return datasetRecord{}, fmt.Errorf("invalid age %q", value)
+		}
+
+		record.Age = &age
+	}
+
+	if value := cell("gender"); value != "" {
+		record.Gender = &value
+	}
+
+	if record.AgeCount, err = atoiOrZero(cell("age_count")); err != nil {
+		return datasetRecord{}, err
+	}
+
+	if record.GenderCount, err = atoiOrZero(cell("gender_count")); err != nil {
+		return datasetRecord{}, err
+	}
+
+	if record.CountryCount, err = atoiOrZero(cell("country_count")); err != nil {
+		return datasetRecord{}, err
+	}
+
+	if value := cell("gender_probability"); value != "" {
+		if record.GenderProbability, err = strconv.ParseFloat(value, 64); err != nil {
+			return datasetRecord{}, fmt.Errorf("invalid gender probability %q", value)
+		}
+	}
+
+	if value := cell("countries"); value != "" {
+		for _, pair := range strings.Split(value, ";") {
+			country, probability, _ := strings.Cut(pair, ":")
+
+			p, err := strconv.ParseFloat(strings.TrimSpace(probability), 64)
+			if err != nil {
+				return datasetRecord{}, fmt.Errorf("invalid country probability %q", pair)
+			}
+
+			record.Countries = append(record.Countries, dto.CountryProbability{
+				CountryID:   strings.TrimSpace(country),
+				Probability: p,
+			})
+		}
+	}
+
+	return record, nil
+}
+
+func atoiOrZero(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid count %q", value)
+	}
+
+	return n, nil
+}
+
+type offline[T any] struct {
+	dataset   *dataset
+	attribute string
+	parse     func(datasetRecord) (T, bool)
+	logger    log.Logger
+}
+
+func newOffline[T any](
+	datasets *datasets,
+	attribute string,
+	parse func(datasetRecord) (T, bool),
+) Factory[T] {
+
+	return func(
+		config config.EnrichmentProvider,
+		_ *http.Client,
+		logger log.Logger,
+	) (Provider[T], error) {
+
+		logger = logger.WithField("provider", "offline")
+
+		d, err := datasets.open(config.Offline, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		return offline[T]{
+			dataset:   d,
+			attribute: attribute,
+			parse:     parse,
+			logger:    logger,
+		}, nil
+	}
+}
+
+// Lookup не учитывает страну: набор содержит общую статистику по имени
+func (o offline[T]) Lookup(
+	_ context.Context,
+	name string,
+	_ string,
+) (T, error) {
+
+	if record, ok := o.dataset.get(name); ok {
+		if value, ok := o.parse(record); ok {
+			return value, nil
+		}
+	}
+
+	o.logger.WithField("name", name).Warn("dataset doesn't know the name")
+
+	var zero T
+
+	return zero, errors.
+		ErrCantEnrichment.
+		New(fmt.Sprintf("can't enrich %s offline", o.attribute)).
+		Wrap(errors.ErrUnknownName.New("unknown name"))
+}
+
+func parseOfflineAge(record datasetRecord) (dto.AgeDTO, bool) {
+	if record.Age == nil {
+		return dto.AgeDTO{}, false
+	}
+
+	return dto.AgeDTO{
+		Age:   *record.Age,
+		Count: record.AgeCount,
+	}, true
+}
+
+func parseOfflineGender(record datasetRecord) (dto.GenderDTO, bool) {
+	if record.Gender == nil {
+		return dto.GenderDTO{}, false
+	}
+
+	return dto.GenderDTO{
+		Gender:      *record.Gender,
+		Probability: record.GenderProbability,
+		Count:       record.GenderCount,
+	}, true
+}
+
+func parseOfflineCountry(record datasetRecord) (dto.CountryDTO, bool) {
+	if len(record.Countries) == 0 {
+		return dto.CountryDTO{}, false
+	}
+
+	top := record.Countries[0]
+
+	for _, country := range record.Countries[1:] {
+		if country.Probability > top.Probability {
+			top = country
+		}
+	}
+
+	return dto.CountryDTO{
+		Country:     top.CountryID,
+		Probability: top.Probability,
+		Count:       record.CountryCount,
+		Countries:   record.Countries,
+	}, true
+}
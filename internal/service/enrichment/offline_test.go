@@ -0,0 +1,310 @@
+package enrichment
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+const testDataset = `name,age,age_count,gender,gender_probability,gender_count,countries,country_count
+Дмитрий,43,100,male,0.99,100,RU:0.6;UA:0.2,100
+Olga,,,female,1,50,,
+`
+
+func writeDataset(
+	t *testing.T,
+	name string,
+	content string,
+) string {
+
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("can't write dataset: %s", err)
+	}
+
+	return path
+}
+
+func TestDatasetNormalizesNames(t *testing.T) {
+	path := writeDataset(t, "names.csv", testDataset)
+
+	tests := []struct {
+		transliteration string
+		name            string
+		found           bool
+	}{
+		{transliteration: TransliterationICAO, name: "Дмитрий", found: true},
+		{transliteration: TransliterationICAO, name: "dmitrii", found: true},
+		{transliteration: TransliterationICAO, name: "  ДМИТРИЙ ", found: true},
+		{transliteration: TransliterationGOST, name: "dmitrij", found: true},
+		{transliteration: TransliterationICAO, name: "dmitriy", found: false},
+		{transliteration: TransliterationNone, name: "дмитрий", found: true},
+		{transliteration: TransliterationICAO, name: "OLGA", found: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.transliteration+" "+tt.name, func(t *testing.T) {
+			n, err := newNormalizer(config.EnrichmentNormalize{Transliteration: tt.transliteration})
+			if err != nil {
+				t.Fatalf("newNormalizer() error: %s", err)
+			}
+
+			d, err := newDataset(config.EnrichmentOffline{Dataset: path}, n, log.NewLogrusLogger())
+			if err != nil {
+				t.Fatalf("newDataset() error: %s", err)
+			}
+
+			// Сервис передаёт провайдеру уже нормализованное имя
+			if _, ok := d.get(n.normalize(tt.name)); ok != tt.found {
+				t.Errorf("get(%q) found = %t, want %t", tt.name, ok, tt.found)
+			}
+		})
+	}
+}
+
+func TestOfflineProvidersShareDataset(t *testing.T) {
+	path := writeDataset(t, "names.csv", testDataset)
+
+	registry := NewRegistry(nil)
+
+	n, err := newNormalizer(config.EnrichmentNormalize{Transliteration: TransliterationICAO})
+	if err != nil {
+		t.Fatalf("newNormalizer() error: %s", err)
+	}
+
+	registry.datasets.normalizer = n
+
+	providerConfig := config.EnrichmentProvider{
+		Provider: "offline",
+		Offline:  config.EnrichmentOffline{Dataset: path},
+	}
+
+	age, err := registry.age["offline"](providerConfig, nil, log.NewLogrusLogger())
+	if err != nil {
+		t.Fatalf("age provider error: %s", err)
+	}
+
+	gender, err := registry.gender["offline"](providerConfig, nil, log.NewLogrusLogger())
+	if err != nil {
+		t.Fatalf("gender provider error: %s", err)
+	}
+
+	if age.(offline[dto.AgeDTO]).dataset != gender.(offline[dto.GenderDTO]).dataset {
+		t.Error("providers of one file use different datasets")
+	}
+
+	value, err := age.Lookup(context.Background(), "dmitrii", "")
+	if err != nil || value.Age != 43 {
+		t.Errorf("Lookup(dmitrii) = %+v, %v, want age 43", value, err)
+	}
+}
+
+func TestReadCSVDataset(t *testing.T) {
+	age, gender := 43, "male"
+
+	tests := []struct {
+		name    string
+		content string
+		want    []datasetRecord
+		err     string
+	}{
+		{
+			name: "full record",
+			content: "name,age,age_count,gender,gender_probability,gender_count,countries,country_count\n" +
+				"Dmitriy,43,100,male,0.99,90,RU:0.6; UA:0.2,80\n",
+			want: []datasetRecord{{
+				Name:              "Dmitriy",
+				Age:               &age,
+				AgeCount:          100,
+				Gender:            &gender,
+				GenderProbability: 0.99,
+				GenderCount:       90,
+				Countries: dto.Countries{
+					{CountryID: "RU", Probability: 0.6},
+					{CountryID: "UA", Probability: 0.2},
+				},
+				CountryCount: 80,
+			}},
+		},
+		{
+			name:    "empty cells mean no data",
+			content: "name,age,gender,countries\nOlga,,,\n",
+			want:    []datasetRecord{{Name: "Olga"}},
+		},
+		{
+			name:    "columns in any order",
+			content: "age, name\n43, Dmitriy\n",
+			want:    []datasetRecord{{Name: "Dmitriy", Age: &age}},
+		},
+		{
+			name:    "header only",
+			content: "name,age\n",
+			want:    []datasetRecord{},
+		},
+		{
+			name:    "missing name column",
+			content: "age,gender\n43,male\n",
+			err:     "missing name column",
+		},
+		{
+			name:    "invalid age",
+			content: "name,age\nDmitriy,43\nOlga,old\n",
+			err:     `line 3: invalid age "old"`,
+		},
+		{
+			name:    "invalid count",
+			content: "name,age_count\nDmitriy,many\n",
+			err:     `invalid count "many"`,
+		},
+		{
+			name:    "invalid gender probability",
+			content: "name,gender_probability\nDmitriy,high\n",
+			err:     `invalid gender probability "high"`,
+		},
+		{
+			name:    "invalid country probability",
+			content: "name,countries\nDmitriy,RU\n",
+			err:     `invalid country probability "RU"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := readCSVDataset(strings.NewReader(tt.content))
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("readCSVDataset() error = %v, want %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("readCSVDataset() error: %s", err)
+			}
+
+			if !reflect.DeepEqual(records, tt.want) {
+				t.Errorf("readCSVDataset() = %+v, want %+v", records, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDatasetRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    func(t *testing.T) string
+		errType *errpkg.Type
+	}{
+		{
+			name:    "empty path",
+			path:    func(*testing.T) string { return "" },
+			errType: errors.ErrEmptyField,
+		},
+		{
+			name:    "missing file",
+			path:    func(t *testing.T) string { return filepath.Join(t.TempDir(), "names.csv") },
+			errType: errors.ErrInternal,
+		},
+		{
+			name:    "unsupported format",
+			path:    func(t *testing.T) string { return writeDataset(t, "names.txt", testDataset) },
+			errType: errors.ErrInvalidValue,
+		},
+		{
+			name:    "broken json",
+			path:    func(t *testing.T) string { return writeDataset(t, "names.json", `[{"name": `) },
+			errType: errors.ErrInvalidValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newDataset(
+				config.EnrichmentOffline{Dataset: tt.path(t)},
+				normalizer{},
+				log.NewLogrusLogger(),
+			)
+
+			if !errpkg.Has(err, tt.errType) {
+				t.Errorf("newDataset() error = %v, want %v", err, tt.errType)
+			}
+		})
+	}
+}
+
+func TestDatasetReload(t *testing.T) {
+	const reloadInterval = 10 * time.Millisecond
+
+	tests := []struct {
+		name           string
+		reloadInterval time.Duration
+		content        string
+		age            int
+	}{
+		{
+			name:           "changed file is reloaded",
+			reloadInterval: reloadInterval,
+			content:        "name,age\nDmitriy,50\n",
+			age:            50,
+		},
+		{
+			name:           "broken file keeps previous data",
+			reloadInterval: reloadInterval,
+			content:        "name,age\nDmitriy,fifty\n",
+			age:            43,
+		},
+		{
+			name:    "reload is disabled",
+			content: "name,age\nDmitriy,50\n",
+			age:     43,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeDataset(t, "names.csv", "name,age\nDmitriy,43\n")
+
+			d, err := newDataset(
+				config.EnrichmentOffline{Dataset: path, ReloadInterval: tt.reloadInterval},
+				normalizer{},
+				log.NewLogrusLogger(),
+			)
+			if err != nil {
+				t.Fatalf("newDataset() error: %s", err)
+			}
+
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("can't write dataset: %s", err)
+			}
+
+			// Время изменения файла может не успеть смениться
+			modTime := time.Now().Add(time.Second)
+
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatalf("can't change dataset time: %s", err)
+			}
+
+			time.Sleep(2 * reloadInterval)
+
+			record, ok := d.get("dmitriy")
+			if !ok || record.Age == nil || *record.Age != tt.age {
+				t.Errorf("get(dmitriy) = %+v, want age %d", record, tt.age)
+			}
+		})
+	}
+}
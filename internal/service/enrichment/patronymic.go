@@ -0,0 +1,45 @@
+package enrichment
+
+import (
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"strings"
+)
+
+// Окончания отчеств однозначно определяют пол, в том числе
+// в латинской записи: Сергеевич/Sergeevich, Ильич/Ilyich,
+// Сергеевна/Sergeevna, Ильинична/Ilyinichna
+var patronymicSuffixes = []struct {
+	suffix string
+	gender string
+}{
+	{"ич", "male"},
+	{"ich", "male"},
+	{"вна", "female"},
+	{"ична", "female"},
+	{"vna", "female"},
+	{"ichna", "female"},
+}
+
+// InferGender определяет пол по окончанию отчества без обращения
+// к провайдерам; false означает, что отчество пол не определяет
+func (s Service) InferGender(
+	patronymic string,
+) (*dto.GenderDTO, bool) {
+
+	patronymic = strings.ToLower(strings.TrimSpace(patronymic))
+	if patronymic == "" {
+		return nil, false
+	}
+
+	for _, rule := range patronymicSuffixes {
+		if strings.HasSuffix(patronymic, rule.suffix) {
+			return &dto.GenderDTO{
+				Gender:      rule.gender,
+				Probability: 1,
+				Source:      dto.SourcePatronymic,
+			}, true
+		}
+	}
+
+	return nil, false
+}
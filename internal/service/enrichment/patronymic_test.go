@@ -0,0 +1,57 @@
+package enrichment
+
+import (
+	"testing"
+
+	"github.com/jackvonhouse/enrichment/internal/dto"
+)
+
+func TestInferGender(t *testing.T) {
+	tests := []struct {
+		patronymic string
+		gender     string
+		ok         bool
+	}{
+		{patronymic: "Сергеевич", gender: "male", ok: true},
+		{patronymic: "Ильич", gender: "male", ok: true},
+		{patronymic: "Vasilevich", gender: "male", ok: true},
+		{patronymic: "Ilyich", gender: "male", ok: true},
+		{patronymic: "Сергеевна", gender: "female", ok: true},
+		{patronymic: "Ильинична", gender: "female", ok: true},
+		{patronymic: "Sergeevna", gender: "female", ok: true},
+		{patronymic: "Ilyinichna", gender: "female", ok: true},
+		{patronymic: "  ИВАНОВИЧ ", gender: "male", ok: true},
+		{patronymic: "PETROVNA", gender: "female", ok: true},
+		{patronymic: "", ok: false},
+		{patronymic: "   ", ok: false},
+		{patronymic: "Оглы", ok: false},
+		{patronymic: "Kyzy", ok: false},
+		// Украинская латиница с окончанием -ych не распознаётся
+		{patronymic: "Ivanovych", ok: false},
+	}
+
+	s := Service{}
+
+	for _, tt := range tests {
+		t.Run(tt.patronymic, func(t *testing.T) {
+			gender, ok := s.InferGender(tt.patronymic)
+			if ok != tt.ok {
+				t.Fatalf("InferGender(%q) ok = %t, want %t", tt.patronymic, ok, tt.ok)
+			}
+
+			if !ok {
+				if gender != nil {
+					t.Errorf("InferGender(%q) = %+v, want nil", tt.patronymic, gender)
+				}
+
+				return
+			}
+
+			want := dto.GenderDTO{Gender: tt.gender, Probability: 1, Source: dto.SourcePatronymic}
+
+			if *gender != want {
+				t.Errorf("InferGender(%q) = %+v, want %+v", tt.patronymic, *gender, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package enrichment
+
+import (
+	"fmt"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"strconv"
+)
+
+const (
+	OnUnknownFail    = "fail"
+	OnUnknownNull    = "null"
+	OnUnknownDefault = "default"
+)
+
+type policy[T any] struct {
+	attribute      string
+	minProbability float64
+	minCount       int
+	onUnknown      string
+	fallback       T
+
+	confidence func(T) (float64, int)
+}
+
+func newPolicy[T any](
+	attribute string,
+	config config.EnrichmentPolicy,
+	fallback func(string) (T, error),
+	confidence func(T) (float64, int),
+) (policy[T], error) {
+
+	p := policy[T]{
+		attribute:      attribute,
+		minProbability: config.MinProbability,
+		minCount:       config.MinCount,
+		onUnknown:      config.OnUnknown,
+		confidence:     confidence,
+	}
+
+	switch config.OnUnknown {
+	case OnUnknownFail, OnUnknownNull:
+	case OnUnknownDefault:
+		value, err := fallback(config.Default)
+		if err != nil {
+			return policy[T]{}, err
+		}
+
+		p.fallback = value
+	default:
+		return policy[T]{}, errors.
+			ErrInvalidValue.
+			New(fmt.Sprintf("unknown %s policy %q", attribute, config.OnUnknown))
+	}
+
+	return p, nil
+}
+
+func newAgePolicy(
+	config config.EnrichmentPolicy,
+) (policy[dto.AgeDTO], error) {
+
+	return newPolicy(
+		"age",
+		config,
+		func(value string) (dto.AgeDTO, error) {
+			age, err := strconv.Atoi(value)
+			if err != nil || age < 0 {
+				return dto.AgeDTO{}, errors.
+					ErrInvalidValue.
+					New(fmt.Sprintf("invalid default age %q", value))
+			}
+
+			return dto.AgeDTO{Age: age, Source: dto.SourceDefault}, nil
+		},
+		// agify не сообщает вероятность, только размер выборки
+		func(age dto.AgeDTO) (float64, int) {
+			return 1, age.Count
+		},
+	)
+}
+
+func newGenderPolicy(
+	config config.EnrichmentPolicy,
+) (policy[dto.GenderDTO], error) {
+
+	return newPolicy(
+		"gender",
+		config,
+		func(value string) (dto.GenderDTO, error) {
+			if value == "" {
+				return dto.GenderDTO{}, errors.
+					ErrEmptyField.
+					New("empty default gender")
+			}
+
+			return dto.GenderDTO{Gender: value, Source: dto.SourceDefault}, nil
+		},
+		func(gender dto.GenderDTO) (float64, int) {
+			return gender.Probability, gender.Count
+		},
+	)
+}
+
+func newCountryPolicy(
+	config config.EnrichmentPolicy,
+) (policy[dto.CountryDTO], error) {
+
+	return newPolicy(
+		"country",
+		config,
+		func(value string) (dto.CountryDTO, error) {
+			if value == "" {
+				return dto.CountryDTO{}, errors.
+					ErrEmptyField.
+					New("empty default country")
+			}
+
+			return dto.CountryDTO{Country: value, Source: dto.SourceDefault}, nil
+		},
+		func(country dto.CountryDTO) (float64, int) {
+			return country.Probability, country.Count
+		},
+	)
+}
+
+// apply возвращает nil, если атрибут по политике остаётся незаполненным.
+func (p policy[T]) apply(
+	value T,
+	err error,
+) (*T, error) {
+
+	if err != nil {
+		if !errpkg.Has(err, errors.ErrUnknownName) {
+			return nil, err
+		}
+
+		return p.unknown(err)
+	}
+
+	probability, count := p.confidence(value)
+
+	if probability < p.minProbability || count < p.minCount {
+		return p.unknown(
+			errors.
+				ErrCantEnrichment.
+				New(fmt.Sprintf("can't enrich %s", p.attribute)).
+				Wrap(
+					errors.ErrLowConfidence.New(fmt.Sprintf(
+						"low confidence: probability %.2f, count %d",
+						probability, count,
+					)),
+				),
+		)
+	}
+
+	return &value, nil
+}
+
+func (p policy[T]) applyBatch(
+	names []string,
+	values map[string]T,
+) map[string]*T {
+
+	result := make(map[string]*T, len(values))
+
+	for _, name := range dedupe(names) {
+		value, ok := values[name]
+
+		var err error
+
+		if !ok {
+			err = errors.ErrUnknownName.New("unknown name")
+		}
+
+		applied, err := p.apply(value, err)
+		if err != nil {
+			continue
+		}
+
+		result[name] = applied
+	}
+
+	return result
+}
+
+func (p policy[T]) unknown(
+	cause error,
+) (*T, error) {
+
+	switch p.onUnknown {
+	case OnUnknownNull:
+		return nil, nil
+
+	case OnUnknownDefault:
+		value := p.fallback
+
+		return &value, nil
+
+	default:
+		return nil, cause
+	}
+}
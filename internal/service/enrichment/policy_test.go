@@ -0,0 +1,179 @@
+package enrichment
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+)
+
+func newTestGenderPolicy(
+	t *testing.T,
+	onUnknown string,
+) policy[dto.GenderDTO] {
+
+	t.Helper()
+
+	p, err := newGenderPolicy(config.EnrichmentPolicy{
+		MinProbability: 0.8,
+		MinCount:       10,
+		OnUnknown:      onUnknown,
+		Default:        "female",
+	})
+	if err != nil {
+		t.Fatalf("newGenderPolicy() error: %s", err)
+	}
+
+	return p
+}
+
+func TestPolicyApply(t *testing.T) {
+	var (
+		confident = dto.GenderDTO{Gender: "male", Probability: 0.95, Count: 100}
+		uncertain = dto.GenderDTO{Gender: "male", Probability: 0.6, Count: 100}
+		rare      = dto.GenderDTO{Gender: "male", Probability: 1, Count: 3}
+		fallback  = dto.GenderDTO{Gender: "female", Source: dto.SourceDefault}
+
+		unknownName = errors.ErrCantEnrichment.New("can't genderize").
+				Wrap(errors.ErrUnknownName.New("unknown name"))
+		circuitOpen = errors.ErrCircuitOpen.New("circuit open")
+	)
+
+	tests := []struct {
+		name      string
+		onUnknown string
+		value     dto.GenderDTO
+		err       error
+		want      *dto.GenderDTO
+		errType   *errpkg.Type
+	}{
+		{name: "confident value", onUnknown: OnUnknownFail, value: confident, want: &confident},
+		{name: "low probability fails", onUnknown: OnUnknownFail, value: uncertain, errType: errors.ErrLowConfidence},
+		{name: "low count fails", onUnknown: OnUnknownFail, value: rare, errType: errors.ErrLowConfidence},
+		{name: "low confidence is null", onUnknown: OnUnknownNull, value: uncertain},
+		{name: "low confidence is default", onUnknown: OnUnknownDefault, value: rare, want: &fallback},
+		{name: "unknown name fails", onUnknown: OnUnknownFail, err: unknownName, errType: errors.ErrUnknownName},
+		{name: "unknown name is null", onUnknown: OnUnknownNull, err: unknownName},
+		{name: "unknown name is default", onUnknown: OnUnknownDefault, err: unknownName, want: &fallback},
+		// Сбой провайдера политикой не подменяется
+		{name: "provider failure", onUnknown: OnUnknownDefault, err: circuitOpen, errType: errors.ErrCircuitOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestGenderPolicy(t, tt.onUnknown).apply(tt.value, tt.err)
+
+			if tt.errType != nil {
+				if !errpkg.Has(err, tt.errType) {
+					t.Errorf("apply() error = %v, want %v", err, tt.errType)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("apply() error: %s", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("apply() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyApplyBatch(t *testing.T) {
+	var (
+		confident = dto.GenderDTO{Gender: "male", Probability: 0.95, Count: 100}
+		uncertain = dto.GenderDTO{Gender: "female", Probability: 0.5, Count: 100}
+		fallback  = dto.GenderDTO{Gender: "female", Source: dto.SourceDefault}
+	)
+
+	names := []string{"ivan", "sasha", "unknown", "ivan"}
+
+	values := map[string]dto.GenderDTO{
+		"ivan":  confident,
+		"sasha": uncertain,
+	}
+
+	tests := []struct {
+		onUnknown string
+		want      map[string]*dto.GenderDTO
+	}{
+		{
+			// Имена с ошибкой в результат не попадают
+			onUnknown: OnUnknownFail,
+			want:      map[string]*dto.GenderDTO{"ivan": &confident},
+		},
+		{
+			onUnknown: OnUnknownNull,
+			want:      map[string]*dto.GenderDTO{"ivan": &confident, "sasha": nil, "unknown": nil},
+		},
+		{
+			onUnknown: OnUnknownDefault,
+			want:      map[string]*dto.GenderDTO{"ivan": &confident, "sasha": &fallback, "unknown": &fallback},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.onUnknown, func(t *testing.T) {
+			got := newTestGenderPolicy(t, tt.onUnknown).applyBatch(names, values)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyBatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPolicyRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func() error
+		errType *errpkg.Type
+	}{
+		{
+			name: "unknown on_unknown",
+			build: func() error {
+				_, err := newAgePolicy(config.EnrichmentPolicy{OnUnknown: "guess"})
+				return err
+			},
+			errType: errors.ErrInvalidValue,
+		},
+		{
+			name: "negative default age",
+			build: func() error {
+				_, err := newAgePolicy(config.EnrichmentPolicy{OnUnknown: OnUnknownDefault, Default: "-1"})
+				return err
+			},
+			errType: errors.ErrInvalidValue,
+		},
+		{
+			name: "empty default gender",
+			build: func() error {
+				_, err := newGenderPolicy(config.EnrichmentPolicy{OnUnknown: OnUnknownDefault})
+				return err
+			},
+			errType: errors.ErrEmptyField,
+		},
+		{
+			name: "empty default country",
+			build: func() error {
+				_, err := newCountryPolicy(config.EnrichmentPolicy{OnUnknown: OnUnknownDefault})
+				return err
+			},
+			errType: errors.ErrEmptyField,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.build(); !errpkg.Has(err, tt.errType) {
+				t.Errorf("error = %v, want %v", err, tt.errType)
+			}
+		})
+	}
+}
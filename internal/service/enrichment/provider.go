@@ -0,0 +1,268 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"net/http"
+	"time"
+)
+
+type Provider[T any] interface {
+	// Lookup принимает код страны для уточнения результата,
+	// пустая строка означает поиск без учёта страны
+	Lookup(context.Context, string, string) (T, error)
+}
+
+type (
+	AgeProvider     = Provider[dto.AgeDTO]
+	GenderProvider  = Provider[dto.GenderDTO]
+	CountryProvider = Provider[dto.CountryDTO]
+)
+
+type healthReporter interface {
+	Health() []dto.ProviderHealth
+}
+
+type cacheStatsReporter interface {
+	CacheStats() []dto.CacheStats
+}
+
+type quotaReporter interface {
+	Quota() []dto.ProviderQuota
+}
+
+type coalescingReporter interface {
+	CoalescingStats() []dto.CoalescingStats
+}
+
+// exhaustionReporter сообщает, что провайдер не примет запрос
+// из-за исчерпанной квоты
+type exhaustionReporter interface {
+	Exhausted() error
+}
+
+func healthOf(provider any) []dto.ProviderHealth {
+	if reporter, ok := provider.(healthReporter); ok {
+		return reporter.Health()
+	}
+
+	return []dto.ProviderHealth{}
+}
+
+func cacheStatsOf(provider any) []dto.CacheStats {
+	if reporter, ok := provider.(cacheStatsReporter); ok {
+		return reporter.CacheStats()
+	}
+
+	return []dto.CacheStats{}
+}
+
+func quotaOf(provider any) []dto.ProviderQuota {
+	if reporter, ok := provider.(quotaReporter); ok {
+		return reporter.Quota()
+	}
+
+	return []dto.ProviderQuota{}
+}
+
+func exhaustedOf(provider any) error {
+	if reporter, ok := provider.(exhaustionReporter); ok {
+		return reporter.Exhausted()
+	}
+
+	return nil
+}
+
+func coalescingStatsOf(provider any) []dto.CoalescingStats {
+	if reporter, ok := provider.(coalescingReporter); ok {
+		return reporter.CoalescingStats()
+	}
+
+	return []dto.CoalescingStats{}
+}
+
+type Factory[T any] func(config.EnrichmentProvider, *http.Client, log.Logger) (Provider[T], error)
+
+type Registry struct {
+	client   *http.Client
+	datasets *datasets
+
+	age     map[string]Factory[dto.AgeDTO]
+	gender  map[string]Factory[dto.GenderDTO]
+	country map[string]Factory[dto.CountryDTO]
+}
+
+func NewRegistry(
+	client *http.Client,
+) *Registry {
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	r := &Registry{
+		client:   client,
+		datasets: newDatasets(),
+		age:      map[string]Factory[dto.AgeDTO]{},
+		gender:   map[string]Factory[dto.GenderDTO]{},
+		country:  map[string]Factory[dto.CountryDTO]{},
+	}
+
+	r.RegisterAge("agify", newAgify)
+	r.RegisterAge("stub", newStubAge)
+	r.RegisterAge("offline", newOffline(r.datasets, "age", parseOfflineAge))
+
+	r.RegisterGender("genderize", newGenderize)
+	r.RegisterGender("stub", newStubGender)
+	r.RegisterGender("offline", newOffline(r.datasets, "gender", parseOfflineGender))
+
+	r.RegisterCountry("nationalize", newNationalize)
+	r.RegisterCountry("stub", newStubCountry)
+	r.RegisterCountry("offline", newOffline(r.datasets, "country", parseOfflineCountry))
+
+	return r
+}
+
+func (r *Registry) RegisterAge(name string, factory Factory[dto.AgeDTO]) {
+	r.age[name] = factory
+}
+
+func (r *Registry) RegisterGender(name string, factory Factory[dto.GenderDTO]) {
+	r.gender[name] = factory
+}
+
+func (r *Registry) RegisterCountry(name string, factory Factory[dto.CountryDTO]) {
+	r.country[name] = factory
+}
+
+func (r *Registry) Age(
+	config config.EnrichmentProvider,
+	logger log.Logger,
+) (AgeProvider, error) {
+
+	return build(
+		r.age,
+		config,
+		r.client,
+		func(value dto.AgeDTO, source string) dto.AgeDTO {
+			value.Source = source
+			return value
+		},
+		logger,
+	)
+}
+
+func (r *Registry) Gender(
+	config config.EnrichmentProvider,
+	logger log.Logger,
+) (GenderProvider, error) {
+
+	return build(
+		r.gender,
+		config,
+		r.client,
+		func(value dto.GenderDTO, source string) dto.GenderDTO {
+			value.Source = source
+			return value
+		},
+		logger,
+	)
+}
+
+func (r *Registry) Country(
+	config config.EnrichmentProvider,
+	logger log.Logger,
+) (CountryProvider, error) {
+
+	return build(
+		r.country,
+		config,
+		r.client,
+		func(value dto.CountryDTO, source string) dto.CountryDTO {
+			value.Source = source
+			return value
+		},
+		logger,
+	)
+}
+
+// build собирает цепочку провайдеров из config.Chain,
+// а если она не задана - из единственного config.Provider;
+// каждое звено получает свои настройки, а с ними свои
+// breaker и limiter
+func build[T any](
+	factories map[string]Factory[T],
+	config config.EnrichmentProvider,
+	client *http.Client,
+	withSource func(T, string) T,
+	logger log.Logger,
+) (Provider[T], error) {
+
+	names := config.Chain
+	if len(names) == 0 {
+		names = []string{config.Provider}
+	}
+
+	links := make([]chainLink[T], 0, len(names))
+
+	for _, name := range names {
+		factory, ok := factories[name]
+		if !ok {
+			logger.Warnf("unknown enrichment provider: %s", name)
+
+			return nil, errors.
+				ErrInvalidValue.
+				New(fmt.Sprintf("unknown enrichment provider %q", name))
+		}
+
+		provider, err := factory(config.Link(name), client, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, chainLink[T]{
+			name:     name,
+			provider: provider,
+		})
+	}
+
+	return chain[T]{
+		links:      links,
+		withSource: withSource,
+		logger:     logger,
+	}, nil
+}
+
+// chainTimeout - наибольшее время опроса всей цепочки: все попытки
+// каждого звена и паузы между ними; 0, если время запроса
+// какого-либо звена не ограничено
+func chainTimeout(
+	config config.EnrichmentProvider,
+) time.Duration {
+
+	names := config.Chain
+	if len(names) == 0 {
+		names = []string{config.Provider}
+	}
+
+	var total time.Duration
+
+	for _, name := range names {
+		link := config.Link(name)
+
+		if link.Timeout <= 0 {
+			return 0
+		}
+
+		attempts := max(link.Retry.MaxAttempts, 1)
+
+		total += time.Duration(attempts)*link.Timeout +
+			time.Duration(attempts-1)*link.Retry.MaxDelay
+	}
+
+	return total
+}
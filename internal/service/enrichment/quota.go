@@ -0,0 +1,110 @@
+package enrichment
+
+import (
+	"fmt"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// quota отслеживает остаток запросов по заголовкам X-Rate-Limit-*
+// и не пускает запросы к провайдеру, пока квота исчерпана
+type quota struct {
+	name string
+
+	mu        *sync.Mutex
+	known     bool
+	limit     int
+	remaining int
+	resetAt   time.Time
+
+	logger log.Logger
+}
+
+func newQuota(
+	name string,
+	logger log.Logger,
+) *quota {
+
+	return &quota{
+		name:   name,
+		mu:     &sync.Mutex{},
+		logger: logger,
+	}
+}
+
+func (q *quota) allow() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.exhausted() {
+		return nil
+	}
+
+	return errors.
+		ErrQuotaExhausted.
+		New(fmt.Sprintf(
+			"%s quota exhausted until %s",
+			q.name, q.resetAt.Format(time.RFC3339),
+		)).
+		Wrap(errors.RetryAfter{Delay: time.Until(q.resetAt)})
+}
+
+func (q *quota) update(
+	header http.Header,
+) {
+
+	remaining, err := strconv.Atoi(header.Get("X-Rate-Limit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.known = true
+	q.remaining = remaining
+
+	if limit, err := strconv.Atoi(header.Get("X-Rate-Limit-Limit")); err == nil {
+		q.limit = limit
+	}
+
+	// Reset - число секунд до начала нового окна
+	if reset, err := strconv.Atoi(header.Get("X-Rate-Limit-Reset")); err == nil {
+		q.resetAt = time.Now().Add(time.Duration(reset) * time.Second)
+	}
+
+	if q.exhausted() {
+		q.logger.Warnf("%s quota exhausted until %s", q.name, q.resetAt.Format(time.RFC3339))
+	}
+}
+
+func (q *quota) state() dto.ProviderQuota {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	state := dto.ProviderQuota{
+		Provider:  q.name,
+		Exhausted: q.exhausted(),
+	}
+
+	if q.known {
+		limit, remaining, resetAt := q.limit, q.remaining, q.resetAt
+
+		state.Limit = &limit
+		state.Remaining = &remaining
+		state.ResetAt = &resetAt
+	}
+
+	return state
+}
+
+// exhausted вызывается под q.mu; после сброса окна запросы
+// снова разрешены до следующего ответа провайдера
+func (q *quota) exhausted() bool {
+	return q.known && q.remaining <= 0 && time.Now().Before(q.resetAt)
+}
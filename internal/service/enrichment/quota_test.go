@@ -0,0 +1,216 @@
+package enrichment
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+func quotaHeader(
+	values ...string,
+) http.Header {
+
+	header := http.Header{}
+
+	for i := 0; i+1 < len(values); i += 2 {
+		header.Set(values[i], values[i+1])
+	}
+
+	return header
+}
+
+func TestQuotaUpdate(t *testing.T) {
+	tests := []struct {
+		name      string
+		headers   []http.Header
+		known     bool
+		limit     int
+		remaining int
+		exhausted bool
+	}{
+		{
+			name:    "no headers",
+			headers: []http.Header{{}},
+		},
+		{
+			name: "quota left",
+			headers: []http.Header{quotaHeader(
+				"X-Rate-Limit-Limit", "1000",
+				"X-Rate-Limit-Remaining", "10",
+				"X-Rate-Limit-Reset", "3600",
+			)},
+			known:     true,
+			limit:     1000,
+			remaining: 10,
+		},
+		{
+			name: "quota exhausted",
+			headers: []http.Header{quotaHeader(
+				"X-Rate-Limit-Limit", "1000",
+				"X-Rate-Limit-Remaining", "0",
+				"X-Rate-Limit-Reset", "3600",
+			)},
+			known:     true,
+			limit:     1000,
+			exhausted: true,
+		},
+		{
+			// Окно уже сброшено, следующий ответ обновит квоту
+			name: "reset window passed",
+			headers: []http.Header{quotaHeader(
+				"X-Rate-Limit-Remaining", "0",
+				"X-Rate-Limit-Reset", "0",
+			)},
+			known: true,
+		},
+		{
+			name: "invalid remaining is ignored",
+			headers: []http.Header{quotaHeader(
+				"X-Rate-Limit-Limit", "1000",
+				"X-Rate-Limit-Remaining", "many",
+				"X-Rate-Limit-Reset", "3600",
+			)},
+		},
+		{
+			name: "headers without remaining keep previous state",
+			headers: []http.Header{
+				quotaHeader(
+					"X-Rate-Limit-Limit", "1000",
+					"X-Rate-Limit-Remaining", "0",
+					"X-Rate-Limit-Reset", "3600",
+				),
+				quotaHeader("X-Rate-Limit-Limit", "500"),
+			},
+			known:     true,
+			limit:     1000,
+			exhausted: true,
+		},
+		{
+			name: "later response restores quota",
+			headers: []http.Header{
+				quotaHeader(
+					"X-Rate-Limit-Remaining", "0",
+					"X-Rate-Limit-Reset", "3600",
+				),
+				quotaHeader(
+					"X-Rate-Limit-Remaining", "5",
+					"X-Rate-Limit-Reset", "3600",
+				),
+			},
+			known:     true,
+			remaining: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newQuota("agify", log.NewLogrusLogger())
+
+			for _, header := range tt.headers {
+				q.update(header)
+			}
+
+			state := q.state()
+
+			if state.Exhausted != tt.exhausted {
+				t.Errorf("state().Exhausted = %t, want %t", state.Exhausted, tt.exhausted)
+			}
+
+			if !tt.known {
+				if state.Limit != nil || state.Remaining != nil || state.ResetAt != nil {
+					t.Errorf("state() = %+v, want unknown quota", state)
+				}
+			} else if state.Limit == nil || *state.Limit != tt.limit ||
+				state.Remaining == nil || *state.Remaining != tt.remaining {
+
+				t.Errorf("state() = %+v, want limit %d and remaining %d", state, tt.limit, tt.remaining)
+			}
+
+			err := q.allow()
+
+			if !tt.exhausted {
+				if err != nil {
+					t.Errorf("allow() error: %s", err)
+				}
+
+				return
+			}
+
+			if !errpkg.Has(err, errors.ErrQuotaExhausted) {
+				t.Fatalf("allow() error = %v, want ErrQuotaExhausted", err)
+			}
+
+			var retryAfter errors.RetryAfter
+
+			if !errpkg.As(err, &retryAfter) || retryAfter.Delay <= 0 || retryAfter.Delay > time.Hour {
+				t.Errorf("allow() retry after = %s, want up to an hour", retryAfter.Delay)
+			}
+		})
+	}
+}
+
+// quotaStub - звено цепочки с исчерпанной или свободной квотой
+type quotaStub struct {
+	linkStub
+	exhausted bool
+}
+
+func (q quotaStub) Exhausted() error {
+	if q.exhausted {
+		return errors.ErrQuotaExhausted.New("quota exhausted")
+	}
+
+	return nil
+}
+
+func TestChainExhausted(t *testing.T) {
+	var (
+		exhausted = quotaStub{exhausted: true}
+		available = quotaStub{}
+		unlimited = linkStub{}
+	)
+
+	tests := []struct {
+		name      string
+		links     []chainLink[dto.AgeDTO]
+		exhausted bool
+	}{
+		{
+			name:      "all links exhausted",
+			links:     []chainLink[dto.AgeDTO]{{"agify", exhausted}, {"offline", exhausted}},
+			exhausted: true,
+		},
+		{
+			name:  "fallback has quota",
+			links: []chainLink[dto.AgeDTO]{{"agify", exhausted}, {"offline", available}},
+		},
+		{
+			// Провайдер без квоты всегда готов принять запрос
+			name:  "fallback without quota",
+			links: []chainLink[dto.AgeDTO]{{"agify", exhausted}, {"stub", unlimited}},
+		},
+		{
+			name:  "first link has quota",
+			links: []chainLink[dto.AgeDTO]{{"agify", available}, {"offline", exhausted}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestChain(tt.links...).Exhausted()
+
+			if tt.exhausted && !errpkg.Has(err, errors.ErrQuotaExhausted) {
+				t.Errorf("Exhausted() error = %v, want ErrQuotaExhausted", err)
+			}
+
+			if !tt.exhausted && err != nil {
+				t.Errorf("Exhausted() error: %s", err)
+			}
+		})
+	}
+}
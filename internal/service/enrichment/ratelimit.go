@@ -0,0 +1,117 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// limiter - token bucket: ёмкость burst, пополнение rate токенов в секунду.
+// Ограничивает исходящие запросы к провайдеру до обращения к нему
+type limiter struct {
+	name  string
+	rate  float64
+	burst float64
+
+	mu       *sync.Mutex
+	tokens   float64
+	updateAt time.Time
+}
+
+func newLimiter(
+	name string,
+	config config.EnrichmentRateLimit,
+) *limiter {
+
+	burst := config.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+
+	return &limiter{
+		name:     name,
+		rate:     config.Rate,
+		burst:    float64(burst),
+		mu:       &sync.Mutex{},
+		tokens:   float64(burst),
+		updateAt: time.Now(),
+	}
+}
+
+// wait забирает токен, дожидаясь его при необходимости. Если токен
+// не появится до дедлайна контекста, возвращается ErrRateLimited
+// без ожидания
+func (l *limiter) wait(
+	ctx context.Context,
+) error {
+
+	if l.rate <= 0 {
+		return nil
+	}
+
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		l.cancel()
+
+		return errors.
+			ErrRateLimited.
+			New(fmt.Sprintf("%s rate limit exceeded", l.name)).
+			Wrap(errors.RetryAfter{Delay: delay})
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		l.cancel()
+
+		return ctx.Err()
+
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve занимает токен, уводя баланс в минус, и возвращает время,
+// через которое занятый токен будет восполнен
+func (l *limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+
+	l.tokens--
+
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(math.Ceil(-l.tokens / l.rate * float64(time.Second)))
+}
+
+// cancel возвращает токен, который так и не был использован
+func (l *limiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+
+	l.tokens = math.Min(l.tokens+1, l.burst)
+}
+
+// refill вызывается под l.mu
+func (l *limiter) refill() {
+	now := time.Now()
+
+	l.tokens = math.Min(l.tokens+now.Sub(l.updateAt).Seconds()*l.rate, l.burst)
+	l.updateAt = now
+}
@@ -0,0 +1,140 @@
+package enrichment
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+)
+
+func (l *limiter) balance() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+
+	return l.tokens
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	l := newLimiter("test", config.EnrichmentRateLimit{Rate: 0, Burst: 1})
+
+	for i := 0; i < 100; i++ {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatalf("wait() error: %s", err)
+		}
+	}
+}
+
+func TestLimiterBurst(t *testing.T) {
+	l := newLimiter("test", config.EnrichmentRateLimit{Rate: 1, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		if delay := l.reserve(); delay != 0 {
+			t.Fatalf("token %d delay = %s, want 0", i+1, delay)
+		}
+	}
+
+	// Четвёртый токен восполнится через секунду при rate = 1
+	delay := l.reserve()
+	if delay < 990*time.Millisecond || delay > time.Second {
+		t.Errorf("delay = %s, want about 1s", delay)
+	}
+
+	// Следующий - ещё через секунду
+	delay = l.reserve()
+	if delay < 1990*time.Millisecond || delay > 2*time.Second {
+		t.Errorf("delay = %s, want about 2s", delay)
+	}
+}
+
+func TestLimiterWaits(t *testing.T) {
+	l := newLimiter("test", config.EnrichmentRateLimit{Rate: 50, Burst: 1})
+
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatalf("wait() error: %s", err)
+		}
+	}
+
+	// Первый токен есть сразу, два следующих - через 20ms каждый
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("3 tokens took %s, want about 40ms", elapsed)
+	}
+}
+
+func TestLimiterRefillIsCapped(t *testing.T) {
+	l := newLimiter("test", config.EnrichmentRateLimit{Rate: 100, Burst: 2})
+
+	l.reserve()
+	l.reserve()
+
+	l.mu.Lock()
+	l.updateAt = time.Now().Add(-time.Hour)
+	l.mu.Unlock()
+
+	if tokens := l.balance(); tokens != 2 {
+		t.Errorf("tokens = %v, want 2", tokens)
+	}
+}
+
+func TestLimiterRejectsBeyondDeadline(t *testing.T) {
+	l := newLimiter("test", config.EnrichmentRateLimit{Rate: 1, Burst: 1})
+
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatalf("wait() error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	err := l.wait(ctx)
+
+	if !errpkg.Has(err, errors.ErrRateLimited) {
+		t.Fatalf("wait() error = %v, want ErrRateLimited", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Millisecond {
+		t.Errorf("rejected after %s, want without waiting", elapsed)
+	}
+
+	var retryAfter errors.RetryAfter
+
+	if !errpkg.As(err, &retryAfter) || retryAfter.Delay <= 0 || retryAfter.Delay > time.Second {
+		t.Errorf("retry after = %s, want up to 1s", retryAfter.Delay)
+	}
+
+	// Неиспользованный токен возвращается
+	if tokens := l.balance(); tokens < 0 || tokens > 0.1 {
+		t.Errorf("tokens = %v, want about 0", tokens)
+	}
+}
+
+func TestLimiterReturnsTokenOnCancel(t *testing.T) {
+	l := newLimiter("test", config.EnrichmentRateLimit{Rate: 1, Burst: 1})
+
+	l.reserve()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	if err := l.wait(ctx); err != context.Canceled {
+		t.Fatalf("wait() error = %v, want %v", err, context.Canceled)
+	}
+
+	if tokens := l.balance(); math.Abs(tokens) > 0.1 {
+		t.Errorf("tokens = %v, want about 0", tokens)
+	}
+}
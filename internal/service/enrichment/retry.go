@@ -0,0 +1,165 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type statusError struct {
+	code       int
+	retryAfter time.Duration
+}
+
+func newStatusError(
+	resp *http.Response,
+) *statusError {
+
+	return &statusError{
+		code:       resp.StatusCode,
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d", e.code)
+}
+
+type retry struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+func newRetry(
+	config config.EnrichmentRetry,
+) retry {
+
+	maxAttempts := config.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	return retry{
+		maxAttempts: maxAttempts,
+		baseDelay:   config.BaseDelay,
+		maxDelay:    config.MaxDelay,
+	}
+}
+
+func (r retry) do(
+	ctx context.Context,
+	attempt func(context.Context) error,
+) error {
+
+	for i := 1; ; i++ {
+		err := attempt(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if i >= r.maxAttempts || !r.retryable(ctx, err) {
+			return err
+		}
+
+		delay := r.delay(i, err)
+
+		// Не ждём, если повтор всё равно не успеет до дедлайна вызывающего
+		// или провайдер просит подождать дольше max_delay: ожидание
+		// только заняло бы обработчик
+		deadline, ok := ctx.Deadline()
+		tooLong := ok && time.Until(deadline) < delay
+
+		if retryAfter := retryAfterOf(err); retryAfter > 0 {
+			if tooLong || (r.maxDelay > 0 && retryAfter > r.maxDelay) {
+				return errors.
+					ErrRateLimited.
+					New(fmt.Sprintf("provider asks to retry after %s", retryAfter)).
+					Wrap(errors.RetryAfter{Delay: retryAfter})
+			}
+		}
+
+		if tooLong {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return err
+
+		case <-timer.C:
+		}
+	}
+}
+
+func (r retry) retryable(
+	ctx context.Context,
+	err error,
+) bool {
+
+	if ctx.Err() != nil {
+		return false
+	}
+
+	// Сетевые ошибки, истечение таймаута отдельной попытки, 429 и 5xx
+	return isProviderFailure(err)
+}
+
+func (r retry) delay(
+	attempt int,
+	err error,
+) time.Duration {
+
+	if retryAfter := retryAfterOf(err); retryAfter > 0 {
+		return retryAfter
+	}
+
+	delay := r.baseDelay << (attempt - 1)
+	if delay <= 0 || (r.maxDelay > 0 && delay > r.maxDelay) {
+		delay = r.maxDelay
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+func retryAfterOf(err error) time.Duration {
+	var statusErr *statusError
+
+	if errpkg.As(err, &statusErr) {
+		return statusErr.retryAfter
+	}
+
+	return 0
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+
+	return 0
+}
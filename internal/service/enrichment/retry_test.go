@@ -0,0 +1,241 @@
+package enrichment
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+)
+
+func TestRetryDelayJitter(t *testing.T) {
+	r := newRetry(config.EnrichmentRetry{
+		MaxAttempts: 5,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    time.Second,
+	})
+
+	tests := []struct {
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{attempt: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{attempt: 2, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{attempt: 3, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		// Экспонента ограничена max_delay
+		{attempt: 5, min: 500 * time.Millisecond, max: time.Second},
+		{attempt: 40, min: 500 * time.Millisecond, max: time.Second},
+	}
+
+	err := &statusError{code: http.StatusServiceUnavailable}
+
+	for _, tt := range tests {
+		seen := map[time.Duration]bool{}
+
+		for i := 0; i < 200; i++ {
+			delay := r.delay(tt.attempt, err)
+
+			if delay < tt.min || delay > tt.max {
+				t.Fatalf("delay(%d) = %s, want between %s and %s", tt.attempt, delay, tt.min, tt.max)
+			}
+
+			seen[delay] = true
+		}
+
+		if len(seen) < 2 {
+			t.Errorf("delay(%d) has no jitter", tt.attempt)
+		}
+	}
+}
+
+func TestRetryDelayHonoursRetryAfter(t *testing.T) {
+	r := newRetry(config.EnrichmentRetry{
+		MaxAttempts: 3,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    time.Second,
+	})
+
+	err := &statusError{code: http.StatusTooManyRequests, retryAfter: 3 * time.Second}
+
+	if delay := r.delay(1, err); delay != 3*time.Second {
+		t.Errorf("delay() = %s, want %s", delay, 3*time.Second)
+	}
+}
+
+func TestRetryDo(t *testing.T) {
+	var (
+		unavailable = &statusError{code: http.StatusServiceUnavailable}
+		notFound    = &statusError{code: http.StatusNotFound}
+		longWait    = &statusError{code: http.StatusTooManyRequests, retryAfter: time.Minute}
+		shortWait   = &statusError{code: http.StatusTooManyRequests, retryAfter: time.Millisecond}
+		throttled   = errors.ErrRateLimited.New("rate limit exceeded")
+	)
+
+	tests := []struct {
+		name        string
+		errs        []error
+		timeout     time.Duration
+		attempts    int
+		err         error
+		rateLimited bool
+		retryAfter  time.Duration
+	}{
+		{
+			name:     "success",
+			attempts: 1,
+		},
+		{
+			name:     "retries server errors",
+			errs:     []error{unavailable, unavailable},
+			attempts: 3,
+		},
+		{
+			name:     "gives up after max attempts",
+			errs:     []error{unavailable, unavailable, unavailable, unavailable},
+			attempts: 3,
+			err:      unavailable,
+		},
+		{
+			name:     "client errors aren't retried",
+			errs:     []error{notFound},
+			attempts: 1,
+			err:      notFound,
+		},
+		{
+			name:     "own throttling isn't retried",
+			errs:     []error{throttled},
+			attempts: 1,
+			err:      throttled,
+		},
+		{
+			name:     "short retry after is waited",
+			errs:     []error{shortWait},
+			attempts: 2,
+		},
+		{
+			name:        "retry after beyond max delay",
+			errs:        []error{longWait},
+			attempts:    1,
+			rateLimited: true,
+			retryAfter:  time.Minute,
+		},
+		{
+			name:        "retry after beyond deadline",
+			errs:        []error{&statusError{code: http.StatusTooManyRequests, retryAfter: 5 * time.Millisecond}},
+			timeout:     2 * time.Millisecond,
+			attempts:    1,
+			rateLimited: true,
+			retryAfter:  5 * time.Millisecond,
+		},
+	}
+
+	r := newRetry(config.EnrichmentRetry{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    10 * time.Millisecond,
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			attempts := 0
+
+			err := r.do(ctx, func(context.Context) error {
+				attempts++
+
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+
+				return nil
+			})
+
+			if attempts != tt.attempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.attempts)
+			}
+
+			if tt.rateLimited {
+				if !errpkg.Has(err, errors.ErrRateLimited) {
+					t.Fatalf("error = %v, want ErrRateLimited", err)
+				}
+
+				var retryAfter errors.RetryAfter
+
+				if !errpkg.As(err, &retryAfter) || retryAfter.Delay != tt.retryAfter {
+					t.Errorf("retry after = %s, want %s", retryAfter.Delay, tt.retryAfter)
+				}
+
+				return
+			}
+
+			if !stderrors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRetryDoStopsOnCancel(t *testing.T) {
+	r := newRetry(config.EnrichmentRetry{
+		MaxAttempts: 5,
+		BaseDelay:   time.Hour,
+		MaxDelay:    time.Hour,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	attempts := 0
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	err := r.do(ctx, func(context.Context) error {
+		attempts++
+
+		return &statusError{code: http.StatusBadGateway}
+	})
+
+	if err == nil || attempts != 1 {
+		t.Errorf("do() = %v after %d attempts, want error after 1 attempt", err, attempts)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{value: "", min: 0, max: 0},
+		{value: "3", min: 3 * time.Second, max: 3 * time.Second},
+		{value: "0", min: 0, max: 0},
+		{value: "-5", min: 0, max: 0},
+		{value: "soon", min: 0, max: 0},
+		{
+			value: time.Now().Add(time.Minute).UTC().Format(http.TimeFormat),
+			min:   58 * time.Second,
+			max:   time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got < tt.min || got > tt.max {
+			t.Errorf("parseRetryAfter(%q) = %s, want between %s and %s", tt.value, got, tt.min, tt.max)
+		}
+	}
+}
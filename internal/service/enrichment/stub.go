@@ -0,0 +1,79 @@
+package enrichment
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"net/http"
+	"strconv"
+)
+
+type stub[T any] struct {
+	value T
+}
+
+func newStubAge(
+	config config.EnrichmentProvider,
+	_ *http.Client,
+	_ log.Logger,
+) (Provider[dto.AgeDTO], error) {
+
+	age, err := strconv.Atoi(config.Value)
+	if err != nil || age < 0 {
+		return nil, errors.
+			ErrInvalidValue.
+			New(fmt.Sprintf("invalid stub age %q", config.Value))
+	}
+
+	return stub[dto.AgeDTO]{value: dto.AgeDTO{Age: age}}, nil
+}
+
+func newStubGender(
+	config config.EnrichmentProvider,
+	_ *http.Client,
+	_ log.Logger,
+) (Provider[dto.GenderDTO], error) {
+
+	if config.Value == "" {
+		return nil, errors.
+			ErrEmptyField.
+			New("empty stub value")
+	}
+
+	return stub[dto.GenderDTO]{
+		value: dto.GenderDTO{
+			Gender:      config.Value,
+			Probability: 1,
+		},
+	}, nil
+}
+
+func newStubCountry(
+	config config.EnrichmentProvider,
+	_ *http.Client,
+	_ log.Logger,
+) (Provider[dto.CountryDTO], error) {
+
+	if config.Value == "" {
+		return nil, errors.
+			ErrEmptyField.
+			New("empty stub value")
+	}
+
+	return stub[dto.CountryDTO]{
+		value: dto.CountryDTO{
+			Country:     config.Value,
+			Probability: 1,
+			Countries: dto.Countries{
+				{CountryID: config.Value, Probability: 1},
+			},
+		},
+	}, nil
+}
+
+func (s stub[T]) Lookup(_ context.Context, _ string, _ string) (T, error) {
+	return s.value, nil
+}
@@ -4,12 +4,19 @@ import (
 	"context"
 	"github.com/jackvonhouse/enrichment/internal/dto"
 	"github.com/jackvonhouse/enrichment/pkg/log"
+	"time"
 )
 
 type repositoryUser interface {
-	Create(context.Context, dto.CreateDTO, dto.EnrichmentDTO) (int, error)
+	Create(context.Context, dto.CreateDTO) (int, error)
 
-	Get(context.Context, dto.GetDTO, dto.FilterDTO, dto.SortDTO) ([]dto.User, error)
+	ClaimPending(context.Context, int, time.Duration) ([]dto.User, error)
+	CompleteEnrichment(context.Context, int, int, dto.EnrichmentDTO) error
+	FailEnrichment(context.Context, int, int, string, *time.Time) error
+	UpdateEnrichment(context.Context, int, dto.EnrichmentDTO, []string) error
+
+	Get(context.Context, dto.GetDTO, dto.FilterDTO, dto.SortDTO) (dto.UserList, error)
+	GetPage(context.Context, dto.PageDTO, dto.FilterDTO, dto.SortDTO) (dto.UserPage, error)
 	GetById(context.Context, int) (dto.User, error)
 
 	Update(context.Context, dto.UpdateDTO) (int, error)
@@ -36,10 +43,49 @@ func New(
 func (s Service) Create(
 	ctx context.Context,
 	create dto.CreateDTO,
-	enrichment dto.EnrichmentDTO,
 ) (int, error) {
 
-	return s.repository.Create(ctx, create, enrichment)
+	return s.repository.Create(ctx, create)
+}
+
+func (s Service) ClaimPending(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]dto.User, error) {
+
+	return s.repository.ClaimPending(ctx, limit, lease)
+}
+
+func (s Service) CompleteEnrichment(
+	ctx context.Context,
+	id int,
+	lease int,
+	enrichment dto.EnrichmentDTO,
+) error {
+
+	return s.repository.CompleteEnrichment(ctx, id, lease, enrichment)
+}
+
+func (s Service) FailEnrichment(
+	ctx context.Context,
+	id int,
+	lease int,
+	reason string,
+	nextAttemptAt *time.Time,
+) error {
+
+	return s.repository.FailEnrichment(ctx, id, lease, reason, nextAttemptAt)
+}
+
+func (s Service) UpdateEnrichment(
+	ctx context.Context,
+	id int,
+	enrichment dto.EnrichmentDTO,
+	attributes []string,
+) error {
+
+	return s.repository.UpdateEnrichment(ctx, id, enrichment, attributes)
 }
 
 func (s Service) Get(
@@ -47,11 +93,21 @@ func (s Service) Get(
 	data dto.GetDTO,
 	filter dto.FilterDTO,
 	sort dto.SortDTO,
-) ([]dto.User, error) {
+) (dto.UserList, error) {
 
 	return s.repository.Get(ctx, data, filter, sort)
 }
 
+func (s Service) GetPage(
+	ctx context.Context,
+	page dto.PageDTO,
+	filter dto.FilterDTO,
+	sort dto.SortDTO,
+) (dto.UserPage, error) {
+
+	return s.repository.GetPage(ctx, page, filter, sort)
+}
+
 func (s Service) GetById(
 	ctx context.Context,
 	id int,
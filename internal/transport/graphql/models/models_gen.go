@@ -2,36 +2,64 @@
 
 package models
 
+type ConditionInput struct {
+	Field    string   `json:"field"`
+	Operator string   `json:"operator"`
+	Values   []string `json:"values,omitempty"`
+}
+
+type CountryProbability struct {
+	CountryID   string  `json:"countryId"`
+	Probability float64 `json:"probability"`
+}
+
 type CreateInput struct {
-	Name       string  `json:"name"`
-	Surname    string  `json:"surname"`
-	Patronymic *string `json:"patronymic,omitempty"`
+	Name        string  `json:"name"`
+	Surname     string  `json:"surname"`
+	Patronymic  *string `json:"patronymic,omitempty"`
+	CountryHint *string `json:"countryHint,omitempty"`
 }
 
 type FilterInput struct {
-	Name       *string  `json:"name,omitempty"`
-	Surname    *string  `json:"surname,omitempty"`
-	Patronymic *string  `json:"patronymic,omitempty"`
-	Age        *int     `json:"age,omitempty"`
-	AgeSort    *string  `json:"ageSort,omitempty"`
-	Gender     []string `json:"gender,omitempty"`
-	Country    []string `json:"country,omitempty"`
+	Name       *string          `json:"name,omitempty"`
+	Surname    *string          `json:"surname,omitempty"`
+	Patronymic *string          `json:"patronymic,omitempty"`
+	Age        *int             `json:"age,omitempty"`
+	AgeSort    *string          `json:"ageSort,omitempty"`
+	Gender     []string         `json:"gender,omitempty"`
+	Country    []string         `json:"country,omitempty"`
+	Conditions []ConditionInput `json:"conditions,omitempty"`
 }
 
 type GetInput struct {
-	Limit  *int `json:"limit,omitempty"`
-	Offset *int `json:"offset,omitempty"`
+	Limit  *int    `json:"limit,omitempty"`
+	Offset *int    `json:"offset,omitempty"`
+	Count  *string `json:"count,omitempty"`
 }
 
 type Mutation struct {
 }
 
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
 type Query struct {
 }
 
 type SortInput struct {
-	SortBy    *string `json:"sortBy,omitempty"`
-	SortOrder *string `json:"sortOrder,omitempty"`
+	SortBy    *string        `json:"sortBy,omitempty"`
+	SortOrder *string        `json:"sortOrder,omitempty"`
+	Keys      []SortKeyInput `json:"keys,omitempty"`
+}
+
+type SortKeyInput struct {
+	Field string  `json:"field"`
+	Order *string `json:"order,omitempty"`
+	Nulls *string `json:"nulls,omitempty"`
 }
 
 type UpdateInput struct {
@@ -45,11 +73,41 @@ type UpdateInput struct {
 }
 
 type User struct {
-	ID         int     `json:"id"`
-	Name       string  `json:"name"`
-	Surname    string  `json:"surname"`
-	Patronymic *string `json:"patronymic,omitempty"`
-	Age        *int    `json:"age,omitempty"`
-	Gender     *string `json:"gender,omitempty"`
-	Country    *string `json:"country,omitempty"`
+	ID                 int                  `json:"id"`
+	Name               string               `json:"name"`
+	Surname            string               `json:"surname"`
+	Patronymic         *string              `json:"patronymic,omitempty"`
+	Age                *int                 `json:"age,omitempty"`
+	Gender             *string              `json:"gender,omitempty"`
+	Country            *string              `json:"country,omitempty"`
+	AgeCount           *int                 `json:"ageCount,omitempty"`
+	GenderProbability  *float64             `json:"genderProbability,omitempty"`
+	GenderCount        *int                 `json:"genderCount,omitempty"`
+	CountryProbability *float64             `json:"countryProbability,omitempty"`
+	CountryCount       *int                 `json:"countryCount,omitempty"`
+	Countries          []CountryProbability `json:"countries,omitempty"`
+	AgeSource          *string              `json:"ageSource,omitempty"`
+	GenderSource       *string              `json:"genderSource,omitempty"`
+	CountrySource      *string              `json:"countrySource,omitempty"`
+	CountryHint        *string              `json:"countryHint,omitempty"`
+	EnrichmentStatus   string               `json:"enrichmentStatus"`
+	EnrichmentError    *string              `json:"enrichmentError,omitempty"`
+}
+
+type UserConnection struct {
+	Edges    []UserEdge `json:"edges"`
+	PageInfo PageInfo   `json:"pageInfo"`
+}
+
+type UserEdge struct {
+	Cursor string `json:"cursor"`
+	Node   User   `json:"node"`
+}
+
+type UserList struct {
+	Items     []User `json:"items"`
+	Total     *int   `json:"total,omitempty"`
+	Estimated bool   `json:"estimated"`
+	Limit     int    `json:"limit"`
+	Offset    int    `json:"offset"`
 }
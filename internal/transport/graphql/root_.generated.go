@@ -41,25 +41,69 @@ type DirectiveRoot struct {
 }
 
 type ComplexityRoot struct {
+	CountryProbability struct {
+		CountryID   func(childComplexity int) int
+		Probability func(childComplexity int) int
+	}
+
 	Mutation struct {
 		Create func(childComplexity int, input models.CreateInput) int
 		Delete func(childComplexity int, id int) int
 		Update func(childComplexity int, input models.UpdateInput) int
 	}
 
+	PageInfo struct {
+		EndCursor       func(childComplexity int) int
+		HasNextPage     func(childComplexity int) int
+		HasPreviousPage func(childComplexity int) int
+		StartCursor     func(childComplexity int) int
+	}
+
 	Query struct {
 		Get     func(childComplexity int, get *models.GetInput, filter *models.FilterInput, sort *models.SortInput) int
 		GetByID func(childComplexity int, id int) int
+		GetList func(childComplexity int, get *models.GetInput, filter *models.FilterInput, sort *models.SortInput) int
+		Users   func(childComplexity int, first *int, after *string, last *int, before *string, filter *models.FilterInput, sort *models.SortInput) int
 	}
 
 	User struct {
-		Age        func(childComplexity int) int
-		Country    func(childComplexity int) int
-		Gender     func(childComplexity int) int
-		ID         func(childComplexity int) int
-		Name       func(childComplexity int) int
-		Patronymic func(childComplexity int) int
-		Surname    func(childComplexity int) int
+		Age                func(childComplexity int) int
+		AgeCount           func(childComplexity int) int
+		AgeSource          func(childComplexity int) int
+		Countries          func(childComplexity int) int
+		Country            func(childComplexity int) int
+		CountryCount       func(childComplexity int) int
+		CountryHint        func(childComplexity int) int
+		CountryProbability func(childComplexity int) int
+		CountrySource      func(childComplexity int) int
+		EnrichmentError    func(childComplexity int) int
+		EnrichmentStatus   func(childComplexity int) int
+		Gender             func(childComplexity int) int
+		GenderCount        func(childComplexity int) int
+		GenderProbability  func(childComplexity int) int
+		GenderSource       func(childComplexity int) int
+		ID                 func(childComplexity int) int
+		Name               func(childComplexity int) int
+		Patronymic         func(childComplexity int) int
+		Surname            func(childComplexity int) int
+	}
+
+	UserConnection struct {
+		Edges    func(childComplexity int) int
+		PageInfo func(childComplexity int) int
+	}
+
+	UserEdge struct {
+		Cursor func(childComplexity int) int
+		Node   func(childComplexity int) int
+	}
+
+	UserList struct {
+		Estimated func(childComplexity int) int
+		Items     func(childComplexity int) int
+		Limit     func(childComplexity int) int
+		Offset    func(childComplexity int) int
+		Total     func(childComplexity int) int
 	}
 }
 
@@ -82,6 +126,20 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 	_ = ec
 	switch typeName + "." + field {
 
+	case "CountryProbability.countryId":
+		if e.complexity.CountryProbability.CountryID == nil {
+			break
+		}
+
+		return e.complexity.CountryProbability.CountryID(childComplexity), true
+
+	case "CountryProbability.probability":
+		if e.complexity.CountryProbability.Probability == nil {
+			break
+		}
+
+		return e.complexity.CountryProbability.Probability(childComplexity), true
+
 	case "Mutation.create":
 		if e.complexity.Mutation.Create == nil {
 			break
@@ -118,6 +176,34 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Mutation.Update(childComplexity, args["input"].(models.UpdateInput)), true
 
+	case "PageInfo.endCursor":
+		if e.complexity.PageInfo.EndCursor == nil {
+			break
+		}
+
+		return e.complexity.PageInfo.EndCursor(childComplexity), true
+
+	case "PageInfo.hasNextPage":
+		if e.complexity.PageInfo.HasNextPage == nil {
+			break
+		}
+
+		return e.complexity.PageInfo.HasNextPage(childComplexity), true
+
+	case "PageInfo.hasPreviousPage":
+		if e.complexity.PageInfo.HasPreviousPage == nil {
+			break
+		}
+
+		return e.complexity.PageInfo.HasPreviousPage(childComplexity), true
+
+	case "PageInfo.startCursor":
+		if e.complexity.PageInfo.StartCursor == nil {
+			break
+		}
+
+		return e.complexity.PageInfo.StartCursor(childComplexity), true
+
 	case "Query.get":
 		if e.complexity.Query.Get == nil {
 			break
@@ -142,6 +228,30 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Query.GetByID(childComplexity, args["id"].(int)), true
 
+	case "Query.getList":
+		if e.complexity.Query.GetList == nil {
+			break
+		}
+
+		args, err := ec.field_Query_getList_args(context.TODO(), rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Query.GetList(childComplexity, args["get"].(*models.GetInput), args["filter"].(*models.FilterInput), args["sort"].(*models.SortInput)), true
+
+	case "Query.users":
+		if e.complexity.Query.Users == nil {
+			break
+		}
+
+		args, err := ec.field_Query_users_args(context.TODO(), rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Query.Users(childComplexity, args["first"].(*int), args["after"].(*string), args["last"].(*int), args["before"].(*string), args["filter"].(*models.FilterInput), args["sort"].(*models.SortInput)), true
+
 	case "User.age":
 		if e.complexity.User.Age == nil {
 			break
@@ -149,6 +259,27 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.User.Age(childComplexity), true
 
+	case "User.ageCount":
+		if e.complexity.User.AgeCount == nil {
+			break
+		}
+
+		return e.complexity.User.AgeCount(childComplexity), true
+
+	case "User.ageSource":
+		if e.complexity.User.AgeSource == nil {
+			break
+		}
+
+		return e.complexity.User.AgeSource(childComplexity), true
+
+	case "User.countries":
+		if e.complexity.User.Countries == nil {
+			break
+		}
+
+		return e.complexity.User.Countries(childComplexity), true
+
 	case "User.country":
 		if e.complexity.User.Country == nil {
 			break
@@ -156,6 +287,48 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.User.Country(childComplexity), true
 
+	case "User.countryCount":
+		if e.complexity.User.CountryCount == nil {
+			break
+		}
+
+		return e.complexity.User.CountryCount(childComplexity), true
+
+	case "User.countryHint":
+		if e.complexity.User.CountryHint == nil {
+			break
+		}
+
+		return e.complexity.User.CountryHint(childComplexity), true
+
+	case "User.countryProbability":
+		if e.complexity.User.CountryProbability == nil {
+			break
+		}
+
+		return e.complexity.User.CountryProbability(childComplexity), true
+
+	case "User.countrySource":
+		if e.complexity.User.CountrySource == nil {
+			break
+		}
+
+		return e.complexity.User.CountrySource(childComplexity), true
+
+	case "User.enrichmentError":
+		if e.complexity.User.EnrichmentError == nil {
+			break
+		}
+
+		return e.complexity.User.EnrichmentError(childComplexity), true
+
+	case "User.enrichmentStatus":
+		if e.complexity.User.EnrichmentStatus == nil {
+			break
+		}
+
+		return e.complexity.User.EnrichmentStatus(childComplexity), true
+
 	case "User.gender":
 		if e.complexity.User.Gender == nil {
 			break
@@ -163,6 +336,27 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.User.Gender(childComplexity), true
 
+	case "User.genderCount":
+		if e.complexity.User.GenderCount == nil {
+			break
+		}
+
+		return e.complexity.User.GenderCount(childComplexity), true
+
+	case "User.genderProbability":
+		if e.complexity.User.GenderProbability == nil {
+			break
+		}
+
+		return e.complexity.User.GenderProbability(childComplexity), true
+
+	case "User.genderSource":
+		if e.complexity.User.GenderSource == nil {
+			break
+		}
+
+		return e.complexity.User.GenderSource(childComplexity), true
+
 	case "User.id":
 		if e.complexity.User.ID == nil {
 			break
@@ -191,6 +385,69 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.User.Surname(childComplexity), true
 
+	case "UserConnection.edges":
+		if e.complexity.UserConnection.Edges == nil {
+			break
+		}
+
+		return e.complexity.UserConnection.Edges(childComplexity), true
+
+	case "UserConnection.pageInfo":
+		if e.complexity.UserConnection.PageInfo == nil {
+			break
+		}
+
+		return e.complexity.UserConnection.PageInfo(childComplexity), true
+
+	case "UserEdge.cursor":
+		if e.complexity.UserEdge.Cursor == nil {
+			break
+		}
+
+		return e.complexity.UserEdge.Cursor(childComplexity), true
+
+	case "UserEdge.node":
+		if e.complexity.UserEdge.Node == nil {
+			break
+		}
+
+		return e.complexity.UserEdge.Node(childComplexity), true
+
+	case "UserList.estimated":
+		if e.complexity.UserList.Estimated == nil {
+			break
+		}
+
+		return e.complexity.UserList.Estimated(childComplexity), true
+
+	case "UserList.items":
+		if e.complexity.UserList.Items == nil {
+			break
+		}
+
+		return e.complexity.UserList.Items(childComplexity), true
+
+	case "UserList.limit":
+		if e.complexity.UserList.Limit == nil {
+			break
+		}
+
+		return e.complexity.UserList.Limit(childComplexity), true
+
+	case "UserList.offset":
+		if e.complexity.UserList.Offset == nil {
+			break
+		}
+
+		return e.complexity.UserList.Offset(childComplexity), true
+
+	case "UserList.total":
+		if e.complexity.UserList.Total == nil {
+			break
+		}
+
+		return e.complexity.UserList.Total(childComplexity), true
+
 	}
 	return 0, false
 }
@@ -199,10 +456,12 @@ func (e *executableSchema) Exec(ctx context.Context) graphql.ResponseHandler {
 	rc := graphql.GetOperationContext(ctx)
 	ec := executionContext{rc, e, 0, 0, make(chan graphql.DeferredResult)}
 	inputUnmarshalMap := graphql.BuildUnmarshalerMap(
+		ec.unmarshalInputConditionInput,
 		ec.unmarshalInputCreateInput,
 		ec.unmarshalInputFilterInput,
 		ec.unmarshalInputGetInput,
 		ec.unmarshalInputSortInput,
+		ec.unmarshalInputSortKeyInput,
 		ec.unmarshalInputUpdateInput,
 	)
 	first := true
@@ -301,7 +560,12 @@ func (ec *executionContext) introspectType(name string) (*introspection.Type, er
 }
 
 var sources = []*ast.Source{
-	{Name: "../../../api/graphql/schema/user.graphql", Input: `type User {
+	{Name: "../../../api/graphql/schema/user.graphql", Input: `type CountryProbability {
+  countryId: String!
+  probability: Float!
+}
+
+type User {
   id: Int!
   name: String!
   surname: String!
@@ -309,12 +573,25 @@ var sources = []*ast.Source{
   age: Int
   gender: String
   country: String
+  ageCount: Int
+  genderProbability: Float
+  genderCount: Int
+  countryProbability: Float
+  countryCount: Int
+  countries: [CountryProbability!]
+  ageSource: String
+  genderSource: String
+  countrySource: String
+  countryHint: String
+  enrichmentStatus: String!
+  enrichmentError: String
 }
 
 input CreateInput {
   name: String!
   surname: String!
   patronymic: String
+  countryHint: String
 }
 
 input UpdateInput {
@@ -327,6 +604,12 @@ input UpdateInput {
   gender: String!
 }
 
+input ConditionInput {
+  field: String!
+  operator: String!
+  values: [String!]
+}
+
 input FilterInput {
   name: String
   surname: String
@@ -335,20 +618,58 @@ input FilterInput {
   ageSort: String
   gender: [String!]
   country: [String!]
+  conditions: [ConditionInput!]
 }
 
 input GetInput {
   limit: Int
   offset: Int
+  count: String
+}
+
+input SortKeyInput {
+  field: String!
+  order: String
+  nulls: String
 }
 
 input SortInput {
   sortBy: String
   sortOrder: String
+  keys: [SortKeyInput!]
+}
+
+type UserList {
+  items: [User!]!
+  total: Int
+  estimated: Boolean!
+  limit: Int!
+  offset: Int!
+}
+
+type UserEdge {
+  cursor: String!
+  node: User!
+}
+
+type PageInfo {
+  hasNextPage: Boolean!
+  hasPreviousPage: Boolean!
+  startCursor: String
+  endCursor: String
+}
+
+type UserConnection {
+  edges: [UserEdge!]!
+  pageInfo: PageInfo!
 }
 
 type Query {
   get(get: GetInput, filter: FilterInput, sort: SortInput): [User!]!
+  # То же, что get, вместе с общим числом строк: к списку, который
+  # возвращает get, нельзя добавить поля без поломки прежних запросов
+  getList(get: GetInput, filter: FilterInput, sort: SortInput): UserList!
+  users(first: Int, after: String, last: Int, before: String, filter: FilterInput, sort: SortInput): UserConnection!
   getById(id: Int!): User!
 }
 
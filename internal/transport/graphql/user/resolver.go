@@ -3,13 +3,16 @@ package user
 import (
 	"context"
 	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/transport"
+	"github.com/jackvonhouse/enrichment/internal/transport/graphql/models"
 	"github.com/jackvonhouse/enrichment/pkg/log"
 )
 
 type useCaseUser interface {
 	Create(context.Context, dto.CreateDTO) (int, error)
 
-	Get(context.Context, dto.GetDTO, dto.FilterDTO, dto.SortDTO) ([]dto.User, error)
+	Get(context.Context, dto.GetDTO, dto.FilterDTO, dto.SortDTO) (dto.UserList, error)
+	GetPage(context.Context, dto.PageDTO, dto.FilterDTO, dto.SortDTO) (dto.UserPage, error)
 	GetById(context.Context, int) (dto.User, error)
 
 	Update(context.Context, dto.UpdateDTO) (int, error)
@@ -33,3 +36,189 @@ func New(
 		logger:  logger.WithField("transport_type", "graphql"),
 	}
 }
+
+// list - общая часть get и getList
+func (r *Resolver) list(
+	ctx context.Context,
+	get dto.GetDTO,
+	filter *models.FilterInput,
+	sort *models.SortInput,
+) (models.UserList, error) {
+
+	users, err := r.useCase.Get(ctx, get, toFilterDTO(filter), toSortDTO(sort))
+	if err != nil {
+		r.logger.Warnf("error getting users: %s", err)
+
+		return models.UserList{}, err
+	}
+
+	list := models.UserList{
+		Items:     make([]models.User, len(users.Items)),
+		Total:     users.Total,
+		Estimated: users.Estimated,
+		Limit:     users.Limit,
+		Offset:    users.Offset,
+	}
+
+	for i, user := range users.Items {
+		list.Items[i] = toUserModel(user)
+	}
+
+	return list, nil
+}
+
+func toUserModel(user dto.User) models.User {
+	var countries []models.CountryProbability
+
+	if user.Countries != nil {
+		countries = make([]models.CountryProbability, len(user.Countries))
+
+		for i, country := range user.Countries {
+			countries[i] = models.CountryProbability{
+				CountryID:   country.CountryID,
+				Probability: country.Probability,
+			}
+		}
+	}
+
+	return models.User{
+		ID:                 user.ID,
+		Name:               user.Name,
+		Surname:            user.Surname,
+		Patronymic:         &user.Patronymic,
+		Age:                user.Age,
+		Gender:             user.Gender,
+		Country:            user.Country,
+		AgeCount:           user.AgeCount,
+		GenderProbability:  user.GenderProbability,
+		GenderCount:        user.GenderCount,
+		CountryProbability: user.CountryProbability,
+		CountryCount:       user.CountryCount,
+		Countries:          countries,
+		AgeSource:          user.AgeSource,
+		GenderSource:       user.GenderSource,
+		CountrySource:      user.CountrySource,
+		CountryHint:        user.CountryHint,
+		EnrichmentStatus:   user.EnrichmentStatus,
+		EnrichmentError:    user.EnrichmentError,
+	}
+}
+
+func toGetDTO(get *models.GetInput) dto.GetDTO {
+	data := dto.GetDTO{
+		Limit:  10,
+		Offset: 0,
+	}
+
+	if get == nil {
+		return data
+	}
+
+	if get.Limit != nil {
+		data.Limit = *get.Limit
+	}
+
+	if get.Offset != nil {
+		data.Offset = *get.Offset
+	}
+
+	if get.Count != nil {
+		data.Count = *get.Count
+	}
+
+	return data
+}
+
+func toFilterDTO(filter *models.FilterInput) dto.FilterDTO {
+	if filter == nil {
+		return dto.FilterDTO{
+			Gender:  []string{},
+			Country: []string{},
+		}
+	}
+
+	var data dto.FilterDTO
+
+	if filter.Name != nil {
+		data.Name = *filter.Name
+	}
+
+	if filter.Surname != nil {
+		data.Surname = *filter.Surname
+	}
+
+	if filter.Patronymic != nil {
+		data.Patronymic = *filter.Patronymic
+	}
+
+	if filter.Age != nil {
+		data.Age = *filter.Age
+	}
+
+	if filter.AgeSort != nil {
+		data.AgeSort = *filter.AgeSort
+	}
+
+	if filter.Gender != nil {
+		data.Gender = make([]string, len(filter.Gender))
+		copy(data.Gender, filter.Gender)
+	}
+
+	if filter.Country != nil {
+		data.Country = make([]string, len(filter.Country))
+		copy(data.Country, filter.Country)
+	}
+
+	for _, condition := range filter.Conditions {
+		data.Conditions = append(data.Conditions, dto.Condition{
+			Field:    condition.Field,
+			Operator: condition.Operator,
+			Values:   condition.Values,
+		})
+	}
+
+	return data
+}
+
+func toSortDTO(sort *models.SortInput) dto.SortDTO {
+	if sort == nil {
+		return transport.DefaultSort
+	}
+
+	// Список ключей важнее устаревшей пары sortBy/sortOrder
+	if sort.Keys != nil {
+		data := dto.SortDTO{
+			Keys: make([]dto.SortKey, len(sort.Keys)),
+		}
+
+		for i, key := range sort.Keys {
+			data.Keys[i] = dto.SortKey{Field: key.Field}
+
+			if key.Order != nil {
+				data.Keys[i].Order = *key.Order
+			}
+
+			if key.Nulls != nil {
+				data.Keys[i].Nulls = *key.Nulls
+			}
+		}
+
+		return data
+	}
+
+	if sort.SortBy == nil && sort.SortOrder == nil {
+		return transport.DefaultSort
+	}
+
+	key := dto.SortKey{Field: "id", Order: dto.SortDesc}
+
+	if sort.SortBy != nil {
+		key.Field = *sort.SortBy
+	}
+
+	if sort.SortOrder != nil {
+		key.Order = *sort.SortOrder
+	}
+
+	return dto.SortDTO{Keys: []dto.SortKey{key}}
+}
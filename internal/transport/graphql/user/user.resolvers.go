@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/jackvonhouse/enrichment/internal/dto"
 	"github.com/jackvonhouse/enrichment/internal/errors"
+	"github.com/jackvonhouse/enrichment/internal/transport"
 	graphql1 "github.com/jackvonhouse/enrichment/internal/transport/graphql"
 	"github.com/jackvonhouse/enrichment/internal/transport/graphql/models"
 )
@@ -15,6 +16,9 @@ var (
 	ErrEmptyGender   = errors.ErrEmptyField.New("empty gender")
 	ErrInvalidUserId = errors.ErrInvalidValue.New("invalid user id")
 	ErrInvalidAge    = errors.ErrInvalidValue.New("invalid age")
+
+	ErrInvalidCountryHint = errors.ErrInvalidValue.New("invalid country hint")
+	ErrInvalidPageSize    = errors.ErrInvalidValue.New("invalid page size")
 )
 
 func (r *mutationResolver) Create(
@@ -34,16 +38,27 @@ func (r *mutationResolver) Create(
 		return 0, ErrEmptySurname
 	}
 
-	var patronymic string
+	var patronymic, countryHint string
 
 	if input.Patronymic != nil {
 		patronymic = *input.Patronymic
 	}
 
+	if input.CountryHint != nil {
+		countryHint = *input.CountryHint
+	}
+
+	if countryHint != "" && !transport.IsCountryCode(countryHint) {
+		r.logger.Warn("invalid country hint")
+
+		return 0, ErrInvalidCountryHint
+	}
+
 	dataInput := dto.CreateDTO{
-		Name:       input.Name,
-		Surname:    input.Surname,
-		Patronymic: patronymic,
+		Name:        input.Name,
+		Surname:     input.Surname,
+		Patronymic:  patronymic,
+		CountryHint: countryHint,
 	}
 
 	return r.useCase.Create(ctx, dataInput)
@@ -56,148 +71,106 @@ func (r *queryResolver) Get(
 	sort *models.SortInput,
 ) ([]models.User, error) {
 
-	var (
-		getInput    dto.GetDTO
-		filterInput dto.FilterDTO
-		sortInput   dto.SortDTO
-	)
+	getInput := toGetDTO(get)
 
-	if get == nil {
-		getInput = dto.GetDTO{
-			Limit:  10,
-			Offset: 0,
-		}
-	} else {
-		var (
-			limit  = 10
-			offset = 0
-		)
-
-		if get.Limit != nil {
-			limit = *get.Limit
-		}
+	// Общее число строк возвращает только getList
+	getInput.Count = dto.CountNone
 
-		if get.Offset != nil {
-			offset = *get.Offset
-		}
+	list, err := r.list(ctx, getInput, filter, sort)
+	if err != nil {
+		return []models.User{}, err
+	}
 
-		getInput = dto.GetDTO{
-			Limit:  limit,
-			Offset: offset,
-		}
+	return list.Items, nil
+}
+
+// GetList - get вместе с общим числом строк. get возвращает список,
+// к которому в GraphQL нельзя добавить поля, а замена его типа
+// на UserList сломала бы существующие запросы
+func (r *queryResolver) GetList(
+	ctx context.Context,
+	get *models.GetInput,
+	filter *models.FilterInput,
+	sort *models.SortInput,
+) (models.UserList, error) {
+
+	getInput := toGetDTO(get)
+
+	if getInput.Count == "" {
+		getInput.Count = dto.CountExact
 	}
 
-	if filter == nil {
-		filterInput = dto.FilterDTO{
-			Name:       "",
-			Surname:    "",
-			Patronymic: "",
-			Age:        0,
-			AgeSort:    "",
-			Gender:     []string{},
-			Country:    []string{},
-		}
-	} else {
-		var (
-			name       = ""
-			surname    = ""
-			patronymic = ""
-			age        = 0
-			ageSort    = ""
-			gender     []string
-			country    []string
-		)
-
-		if filter.Name != nil {
-			name = *filter.Name
-		}
+	return r.list(ctx, getInput, filter, sort)
+}
 
-		if filter.Surname != nil {
-			surname = *filter.Surname
-		}
+func (r *queryResolver) Users(
+	ctx context.Context,
+	first *int,
+	after *string,
+	last *int,
+	before *string,
+	filter *models.FilterInput,
+	sort *models.SortInput,
+) (models.UserConnection, error) {
 
-		if filter.Patronymic != nil {
-			patronymic = *filter.Patronymic
-		}
+	page := dto.PageDTO{Limit: 10}
 
-		if filter.Age != nil {
-			age = *filter.Age
+	switch {
+	case first != nil || after != nil:
+		if first != nil {
+			page.Limit = *first
 		}
 
-		if filter.AgeSort != nil {
-			ageSort = *filter.AgeSort
+		if after != nil {
+			page.Cursor = *after
 		}
 
-		if filter.Gender != nil {
-			gender = make([]string, len(filter.Gender))
-			copy(gender, filter.Gender)
-		}
+	case last != nil || before != nil:
+		page.Backward = true
 
-		if filter.Country != nil {
-			country = make([]string, len(filter.Country))
-			copy(country, filter.Country)
+		if last != nil {
+			page.Limit = *last
 		}
 
-		filterInput = dto.FilterDTO{
-			Name:       name,
-			Surname:    surname,
-			Patronymic: patronymic,
-			Age:        age,
-			AgeSort:    ageSort,
-			Gender:     gender,
-			Country:    country,
+		if before != nil {
+			page.Cursor = *before
 		}
 	}
 
-	if sort == nil {
-		sortInput = dto.SortDTO{
-			SortBy:    "id",
-			SortOrder: "desc",
-		}
-	} else {
-		var (
-			sortBy    = "id"
-			sortOrder = "desc"
-		)
-
-		if sort.SortBy != nil {
-			sortBy = *sort.SortBy
-		}
+	if page.Limit <= 0 {
+		r.logger.Warn("invalid page size")
 
-		if sort.SortOrder != nil {
-			sortOrder = *sort.SortOrder
-		}
-
-		sortInput = dto.SortDTO{
-			SortBy:    sortBy,
-			SortOrder: sortOrder,
-		}
+		return models.UserConnection{}, ErrInvalidPageSize
 	}
 
-	users, err := r.useCase.Get(ctx, getInput, filterInput, sortInput)
+	users, err := r.useCase.GetPage(ctx, page, toFilterDTO(filter), toSortDTO(sort))
 	if err != nil {
-		r.logger.Warnf("error getting users: %s", err)
+		r.logger.Warnf("error getting users page: %s", err)
 
-		return []models.User{}, err
+		return models.UserConnection{}, err
 	}
 
-	usersModel := make([]models.User, len(users))
-
-	for i, user := range users {
-		user := user
+	connection := models.UserConnection{
+		Edges: make([]models.UserEdge, len(users.Items)),
+		PageInfo: models.PageInfo{
+			HasNextPage:     users.NextCursor != nil,
+			HasPreviousPage: users.PrevCursor != nil,
+		},
+	}
 
-		usersModel[i] = models.User{
-			ID:         user.ID,
-			Name:       user.Name,
-			Surname:    user.Surname,
-			Patronymic: &user.Patronymic,
-			Age:        &user.Age,
-			Country:    &user.Country,
-			Gender:     &user.Gender,
+	for i, user := range users.Items {
+		connection.Edges[i] = models.UserEdge{
+			Cursor: users.Cursors[i],
+			Node:   toUserModel(user),
 		}
 	}
 
-	return usersModel, nil
+	if n := len(users.Cursors); n > 0 {
+		connection.PageInfo.StartCursor = &users.Cursors[0]
+		connection.PageInfo.EndCursor = &users.Cursors[n-1]
+	}
+
+	return connection, nil
 }
 
 func (r *queryResolver) GetByID(
@@ -218,15 +191,7 @@ func (r *queryResolver) GetByID(
 		return models.User{}, err
 	}
 
-	return models.User{
-		ID:         user.ID,
-		Name:       user.Name,
-		Surname:    user.Surname,
-		Patronymic: &user.Patronymic,
-		Age:        &user.Age,
-		Gender:     &user.Gender,
-		Country:    &user.Country,
-	}, nil
+	return toUserModel(user), nil
 }
 
 func (r *mutationResolver) Update(
@@ -0,0 +1,175 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/transport"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+type useCaseAdmin interface {
+	Quota(context.Context) []dto.ProviderQuota
+
+	StartReenrich(context.Context, dto.ReenrichDTO) (dto.ReenrichReport, error)
+	ReenrichStatus(context.Context) (dto.ReenrichReport, error)
+}
+
+type Transport struct {
+	useCase useCaseAdmin
+
+	// Пустой токен отключает эндпоинты
+	token string
+
+	logger log.Logger
+}
+
+func New(
+	useCase useCaseAdmin,
+	token string,
+	logger log.Logger,
+) Transport {
+
+	return Transport{
+		useCase: useCase,
+		token:   token,
+		logger:  logger.WithField("transport_type", "http"),
+	}
+}
+
+func (t Transport) Handle(
+	router *mux.Router,
+) {
+
+	router.Use(t.authorize)
+
+	router.HandleFunc("/quota", t.Quota).
+		Methods(http.MethodGet)
+
+	router.HandleFunc("/reenrich", t.StartReenrich).
+		Methods(http.MethodPost)
+
+	router.HandleFunc("/reenrich", t.ReenrichStatus).
+		Methods(http.MethodGet)
+}
+
+// authorize пропускает только запросы с заголовком
+// "Authorization: Bearer <token>"
+func (t Transport) authorize(
+	next http.Handler,
+) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t.token == "" {
+			transport.Error(w, http.StatusForbidden, "admin api is disabled")
+
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(t.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			transport.Error(w, http.StatusUnauthorized, "invalid admin token")
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (t Transport) Quota(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	transport.Response(w, t.useCase.Quota(ctx))
+}
+
+// StartReenrich принимает те же фильтры, что и получение пользователей,
+// а также batch_size и dry_run
+func (t Transport) StartReenrich(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	queries := r.URL.Query()
+
+	batchSize := 0
+
+	if value := queries.Get("batch_size"); value != "" {
+		size, err := transport.StringToInt(value)
+		if err != nil || size <= 0 {
+			transport.Error(w, http.StatusBadRequest, "invalid batch size")
+
+			return
+		}
+
+		batchSize = size
+	}
+
+	dryRun := false
+
+	if value := queries.Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			transport.Error(w, http.StatusBadRequest, "invalid dry run")
+
+			return
+		}
+
+		dryRun = parsed
+	}
+
+	data := dto.ReenrichDTO{
+		Filter:    transport.FilterFromQuery(queries),
+		BatchSize: batchSize,
+		DryRun:    dryRun,
+	}
+
+	report, err := t.useCase.StartReenrich(r.Context(), data)
+	if err != nil {
+		t.logger.Warn(err)
+
+		code, msg := transport.ErrorToHttpResponse(
+			err,
+			transport.DefaultErrorHttpCodes,
+		)
+
+		transport.SetRetryAfter(w, err)
+		transport.Error(w, code, msg)
+
+		return
+	}
+
+	transport.ResponseStatus(w, http.StatusAccepted, report)
+}
+
+func (t Transport) ReenrichStatus(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	report, err := t.useCase.ReenrichStatus(r.Context())
+	if err != nil {
+		code, msg := transport.ErrorToHttpResponse(
+			err,
+			transport.DefaultErrorHttpCodes,
+		)
+
+		transport.Error(w, code, msg)
+
+		return
+	}
+
+	transport.Response(w, report)
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/transport"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+type useCaseHealth interface {
+	Check(context.Context) dto.Health
+}
+
+type Transport struct {
+	useCase useCaseHealth
+
+	logger log.Logger
+}
+
+func New(
+	useCase useCaseHealth,
+	logger log.Logger,
+) Transport {
+
+	return Transport{
+		useCase: useCase,
+		logger:  logger.WithField("transport_type", "http"),
+	}
+}
+
+func (t Transport) Handle(
+	router *mux.Router,
+) {
+
+	router.HandleFunc("", t.Check).
+		Methods(http.MethodGet)
+}
+
+func (t Transport) Check(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	transport.Response(w, t.useCase.Check(ctx))
+}
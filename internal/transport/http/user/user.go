@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -15,7 +17,8 @@ import (
 type useCaseUser interface {
 	Create(context.Context, dto.CreateDTO) (int, error)
 
-	Get(context.Context, dto.GetDTO, dto.FilterDTO, dto.SortDTO) ([]dto.User, error)
+	Get(context.Context, dto.GetDTO, dto.FilterDTO, dto.SortDTO) (dto.UserList, error)
+	GetPage(context.Context, dto.PageDTO, dto.FilterDTO, dto.SortDTO) (dto.UserPage, error)
 	GetById(context.Context, int) (dto.User, error)
 
 	Update(context.Context, dto.UpdateDTO) (int, error)
@@ -85,6 +88,12 @@ func (t Transport) Create(
 		return
 	}
 
+	if data.CountryHint != "" && !transport.IsCountryCode(data.CountryHint) {
+		transport.Error(w, http.StatusBadRequest, "invalid country hint")
+
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
@@ -102,7 +111,8 @@ func (t Transport) Create(
 		return
 	}
 
-	transport.Response(w, map[string]any{"id": id})
+	// Обогащение выполняется асинхронно, статус доступен в enrichment_status
+	transport.ResponseStatus(w, http.StatusAccepted, map[string]any{"id": id})
 }
 
 func (t Transport) Get(
@@ -112,44 +122,9 @@ func (t Transport) Get(
 
 	queries := r.URL.Query()
 
-	name := queries.Get("name")
-	surname := queries.Get("surname")
-	patronymic := queries.Get("patronymic")
-
-	age, err := transport.StringToInt(queries.Get("age"))
-	if err != nil || age < 0 {
-		age = 0
-	}
-
-	ageSort := queries.Get("age_sort_operator")
+	filter := transport.FilterFromQuery(queries)
 
-	genders := queries["gender"]
-	countries := queries["country"]
-
-	filter := dto.FilterDTO{
-		Name:       name,
-		Surname:    surname,
-		Patronymic: patronymic,
-		Age:        age,
-		AgeSort:    ageSort,
-		Gender:     genders,
-		Country:    countries,
-	}
-
-	sortBy := queries.Get("sort_by")
-	if !transport.IsSortField(sortBy) {
-		sortBy = "id"
-	}
-
-	sortOrder := queries.Get("sort_order")
-	if !transport.IsSortOrder(sortOrder) {
-		sortOrder = "desc"
-	}
-
-	sort := dto.SortDTO{
-		SortBy:    sortBy,
-		SortOrder: sortOrder,
-	}
+	sort := transport.SortFromQuery(queries)
 
 	limit, err := transport.StringToInt(queries.Get("limit"))
 	if err != nil || limit <= 0 {
@@ -171,13 +146,35 @@ func (t Transport) Get(
 		offset = 0
 	}
 
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	// Параметр cursor включает выборку по курсору, для первой
+	// страницы он передаётся пустым: ?cursor=
+	if queries.Has("cursor") {
+		t.getPage(ctx, w, queries, limit, filter, sort)
+
+		return
+	}
+
+	// Конверт с общим числом строк и ссылками включается явно,
+	// чтобы не менять ответ для существующих клиентов
+	envelope, err := strconv.ParseBool(queries.Get("envelope"))
+	if err != nil {
+		envelope = false
+	}
+
 	data := dto.GetDTO{
 		Limit:  limit,
 		Offset: offset,
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
+	if envelope {
+		data.Count = queries.Get("count")
+		if data.Count == "" {
+			data.Count = dto.CountExact
+		}
+	}
 
 	users, err := t.useCase.Get(ctx, data, filter, sort)
 	if err != nil {
@@ -193,6 +190,52 @@ func (t Transport) Get(
 		return
 	}
 
+	if !envelope {
+		transport.Response(w, users.Items)
+
+		return
+	}
+
+	transport.Response(w, listEnvelope{
+		UserList: users,
+		Links:    transport.OffsetLinks(r.URL, users.Limit, users.Offset, len(users.Items), users.Total),
+	})
+}
+
+type listEnvelope struct {
+	dto.UserList
+
+	Links transport.Links `json:"links"`
+}
+
+func (t Transport) getPage(
+	ctx context.Context,
+	w http.ResponseWriter,
+	queries url.Values,
+	limit int,
+	filter dto.FilterDTO,
+	sort dto.SortDTO,
+) {
+
+	page := dto.PageDTO{
+		Limit:  limit,
+		Cursor: queries.Get("cursor"),
+	}
+
+	users, err := t.useCase.GetPage(ctx, page, filter, sort)
+	if err != nil {
+		t.logger.Warn(err)
+
+		code, msg := transport.ErrorToHttpResponse(
+			err,
+			transport.DefaultErrorHttpCodes,
+		)
+
+		transport.Error(w, code, msg)
+
+		return
+	}
+
 	transport.Response(w, users)
 }
 
@@ -0,0 +1,292 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"github.com/jackvonhouse/enrichment/internal/transport"
+	"github.com/jackvonhouse/enrichment/pkg/broker"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+const (
+	// Пауза перед повтором после ошибки брокера
+	retryDelay = time.Second
+
+	// Наибольшая пауза между повторами сохранения пользователя
+	maxRetryDelay = 30 * time.Second
+)
+
+type useCaseUser interface {
+	Create(context.Context, dto.CreateDTO) (int, error)
+}
+
+type failedMessage struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+type Transport struct {
+	broker  broker.Broker
+	useCase useCaseUser
+	config  config.Kafka
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     *sync.WaitGroup
+
+	logger log.Logger
+}
+
+func New(
+	broker broker.Broker,
+	useCase useCaseUser,
+	config config.Kafka,
+	logger log.Logger,
+) *Transport {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Transport{
+		broker:  broker,
+		useCase: useCase,
+		config:  config,
+		ctx:     ctx,
+		cancel:  cancel,
+		wg:      &sync.WaitGroup{},
+		logger: logger.WithFields(map[string]any{
+			"transport_type": "kafka",
+			"topic":          config.Topic,
+		}),
+	}
+}
+
+func (t *Transport) Run() {
+	t.wg.Add(1)
+
+	go t.consume()
+}
+
+// Shutdown прекращает чтение топика, дожидается обработки текущего
+// сообщения и закрывает брокер; незакоммиченное сообщение будет
+// прочитано повторно после перезапуска
+func (t *Transport) Shutdown(
+	ctx context.Context,
+) error {
+
+	t.cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return t.broker.Close()
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Transport) consume() {
+	defer t.wg.Done()
+
+	for {
+		msg, err := t.broker.Fetch(t.ctx)
+		if err != nil {
+			if t.ctx.Err() != nil {
+				return
+			}
+
+			t.logger.Warnf("can't fetch message: %s", err)
+
+			if !t.wait() {
+				return
+			}
+
+			continue
+		}
+
+		if !t.handle(msg) {
+			return
+		}
+
+		if err := t.commit(msg); err != nil {
+			t.logger.Warnf("can't commit message: %s", err)
+		}
+
+		if t.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// commit не зависит от остановки: обработанное сообщение коммитится,
+// даже если Shutdown начался во время его обработки
+func (t *Transport) commit(
+	msg broker.Message,
+) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.config.Timeout)
+	defer cancel()
+
+	return t.broker.Commit(ctx, msg)
+}
+
+// handle создаёт пользователя из сообщения. Невалидное сообщение
+// перекладывается в топик ошибок, уже существующий пользователь
+// считается успехом (сообщение могло быть прочитано повторно),
+// остальные ошибки повторяются с растущей паузой; false означает,
+// что сообщение не обработано из-за остановки и коммитить его нельзя
+func (t *Transport) handle(
+	msg broker.Message,
+) bool {
+
+	delay := retryDelay
+
+	for {
+		err := t.create(msg)
+		if err == nil {
+			return true
+		}
+
+		if errpkg.Has(err, errors.ErrAlreadyExists) {
+			t.logger.Infof("user from message (offset %d) already exists: %s", msg.Offset, err)
+
+			return true
+		}
+
+		if permanent(err) {
+			t.logger.Warnf("can't create user from message (offset %d): %s", msg.Offset, err)
+
+			return t.fail(msg, err)
+		}
+
+		t.logger.Warnf("can't create user from message (offset %d), retrying in %s: %s", msg.Offset, delay, err)
+
+		if !t.sleep(delay) {
+			return false
+		}
+
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+func (t *Transport) create(
+	msg broker.Message,
+) error {
+
+	data, err := t.decode(msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(t.ctx, t.config.Timeout)
+	defer cancel()
+
+	_, err = t.useCase.Create(ctx, data)
+
+	return err
+}
+
+// permanent - ошибка, которую повтор не исправит
+func permanent(err error) bool {
+	return errpkg.Has(err, errors.ErrEmptyField) ||
+		errpkg.Has(err, errors.ErrInvalidValue)
+}
+
+func (t *Transport) decode(
+	msg broker.Message,
+) (dto.CreateDTO, error) {
+
+	data := dto.CreateDTO{}
+
+	if err := json.Unmarshal(msg.Value, &data); err != nil {
+		return dto.CreateDTO{}, errors.ErrInvalidValue.New("invalid json structure").Wrap(err)
+	}
+
+	if data.Name == "" {
+		return dto.CreateDTO{}, errors.ErrEmptyField.New("empty name")
+	}
+
+	if data.Surname == "" {
+		return dto.CreateDTO{}, errors.ErrEmptyField.New("empty surname")
+	}
+
+	if data.CountryHint != "" && !transport.IsCountryCode(data.CountryHint) {
+		return dto.CreateDTO{}, errors.ErrInvalidValue.New("invalid country hint")
+	}
+
+	return data, nil
+}
+
+// fail публикует сообщение с причиной ошибки, повторяя попытки до успеха
+// или остановки, чтобы не потерять сообщение при недоступности брокера
+func (t *Transport) fail(
+	msg broker.Message,
+	reason error,
+) bool {
+
+	value, err := json.Marshal(failedMessage{
+		Message: string(msg.Value),
+		Error:   reason.Error(),
+	})
+
+	if err != nil {
+		t.logger.Warnf("can't marshal failed message: %s", err)
+
+		return true
+	}
+
+	failed := broker.Message{
+		Key:   msg.Key,
+		Value: value,
+		Headers: map[string]string{
+			"error": reason.Error(),
+		},
+	}
+
+	for {
+		err := t.broker.Publish(t.ctx, t.config.FailedTopic, failed)
+		if err == nil {
+			return true
+		}
+
+		if t.ctx.Err() != nil {
+			return false
+		}
+
+		t.logger.Warnf("can't publish failed message: %s", err)
+
+		if !t.wait() {
+			return false
+		}
+	}
+}
+
+func (t *Transport) wait() bool {
+	return t.sleep(retryDelay)
+}
+
+func (t *Transport) sleep(
+	delay time.Duration,
+) bool {
+
+	select {
+	case <-t.ctx.Done():
+		return false
+
+	case <-time.After(delay):
+		return true
+	}
+}
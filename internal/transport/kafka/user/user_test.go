@@ -0,0 +1,256 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"github.com/jackvonhouse/enrichment/pkg/broker"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+// useCaseStub возвращает ошибки из errs по очереди,
+// а после них создаёт пользователя и вызывает onCreate
+type useCaseStub struct {
+	mu       sync.Mutex
+	errs     []error
+	created  []dto.CreateDTO
+	onCreate func()
+}
+
+func (u *useCaseStub) Create(
+	_ context.Context,
+	data dto.CreateDTO,
+) (int, error) {
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if len(u.errs) > 0 {
+		err := u.errs[0]
+		u.errs = u.errs[1:]
+
+		return 0, err
+	}
+
+	u.created = append(u.created, data)
+
+	if u.onCreate != nil {
+		u.onCreate()
+	}
+
+	return len(u.created), nil
+}
+
+func newTestTransport(
+	useCase useCaseUser,
+) (*Transport, *broker.Memory) {
+
+	b := broker.NewMemory("FIO")
+
+	t := New(b, useCase, config.Kafka{
+		Topic:       "FIO",
+		FailedTopic: "FIO_FAILED",
+		Timeout:     time.Second,
+	}, log.NewLogrusLogger())
+
+	return t, b
+}
+
+func TestHandle(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		errs      []error
+		created   int
+		failedErr string
+	}{
+		{
+			name:    "valid message",
+			value:   `{"name": "Dmitriy", "surname": "Ushakov", "patronymic": "Vasilevich"}`,
+			created: 1,
+		},
+		{
+			name:      "invalid json",
+			value:     `{"name": `,
+			failedErr: "invalid json structure",
+		},
+		{
+			name:      "empty name",
+			value:     `{"surname": "Ushakov"}`,
+			failedErr: "empty name",
+		},
+		{
+			name:      "empty surname",
+			value:     `{"name": "Dmitriy"}`,
+			failedErr: "empty surname",
+		},
+		{
+			name:      "invalid country hint",
+			value:     `{"name": "Dmitriy", "surname": "Ushakov", "country_hint": "Russia"}`,
+			failedErr: "invalid country hint",
+		},
+		{
+			// Повторно прочитанное сообщение
+			name:  "already exists",
+			value: `{"name": "Dmitriy", "surname": "Ushakov"}`,
+			errs:  []error{errors.ErrAlreadyExists.New("user already exists")},
+		},
+		{
+			name:    "transient error is retried",
+			value:   `{"name": "Dmitriy", "surname": "Ushakov"}`,
+			errs:    []error{errors.ErrInternal.New("internal error")},
+			created: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCase := &useCaseStub{errs: tt.errs}
+			transport, b := newTestTransport(useCase)
+
+			msg := broker.Message{Key: []byte("key"), Value: []byte(tt.value)}
+
+			if !transport.handle(msg) {
+				t.Fatal("message isn't handled")
+			}
+
+			if len(useCase.created) != tt.created {
+				t.Errorf("created %d users, want %d", len(useCase.created), tt.created)
+			}
+
+			failed := b.Messages("FIO_FAILED")
+
+			if tt.failedErr == "" {
+				if len(failed) != 0 {
+					t.Errorf("unexpected failed messages: %d", len(failed))
+				}
+
+				return
+			}
+
+			if len(failed) != 1 {
+				t.Fatalf("got %d failed messages, want 1", len(failed))
+			}
+
+			var got failedMessage
+
+			if err := json.Unmarshal(failed[0].Value, &got); err != nil {
+				t.Fatalf("can't decode failed message: %s", err)
+			}
+
+			if got.Message != tt.value {
+				t.Errorf("failed message is %q, want %q", got.Message, tt.value)
+			}
+
+			if !strings.Contains(got.Error, tt.failedErr) {
+				t.Errorf("failed message error is %q, want %q", got.Error, tt.failedErr)
+			}
+
+			if failed[0].Headers["error"] != got.Error {
+				t.Errorf("error header is %q, want %q", failed[0].Headers["error"], got.Error)
+			}
+
+			if string(failed[0].Key) != "key" {
+				t.Errorf("failed message key is %q, want %q", failed[0].Key, "key")
+			}
+		})
+	}
+}
+
+func TestHandleStopsOnShutdown(t *testing.T) {
+	useCase := &useCaseStub{errs: []error{
+		errors.ErrInternal.New("internal error"),
+	}}
+
+	transport, b := newTestTransport(useCase)
+	transport.cancel()
+
+	msg := broker.Message{Value: []byte(`{"name": "Dmitriy", "surname": "Ushakov"}`)}
+
+	if transport.handle(msg) {
+		t.Error("message is handled after shutdown")
+	}
+
+	if failed := b.Messages("FIO_FAILED"); len(failed) != 0 {
+		t.Errorf("unexpected failed messages: %d", len(failed))
+	}
+}
+
+func TestConsumeCommits(t *testing.T) {
+	useCase := &useCaseStub{}
+	transport, b := newTestTransport(useCase)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	transport.Run()
+
+	values := []string{
+		`{"name": "Dmitriy", "surname": "Ushakov"}`,
+		`{"name": "Dmitriy"}`,
+		`{"name": "Ivan", "surname": "Petrov"}`,
+	}
+
+	for i, value := range values {
+		msg := broker.Message{Key: []byte(fmt.Sprint(i)), Value: []byte(value)}
+
+		if err := b.Publish(ctx, "FIO", msg); err != nil {
+			t.Fatalf("can't publish message: %s", err)
+		}
+	}
+
+	if err := b.WaitCommitted(ctx, int64(len(values)-1)); err != nil {
+		t.Fatalf("messages aren't committed: %s", err)
+	}
+
+	if err := transport.Shutdown(ctx); err != nil {
+		t.Fatalf("can't shutdown: %s", err)
+	}
+
+	if len(useCase.created) != 2 {
+		t.Errorf("created %d users, want 2", len(useCase.created))
+	}
+
+	if failed := b.Messages("FIO_FAILED"); len(failed) != 1 {
+		t.Errorf("got %d failed messages, want 1", len(failed))
+	}
+}
+
+func TestConsumeCommitsDuringShutdown(t *testing.T) {
+	useCase := &useCaseStub{}
+	transport, b := newTestTransport(useCase)
+
+	// Остановка начинается, пока сообщение обрабатывается
+	useCase.onCreate = transport.cancel
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	msg := broker.Message{Value: []byte(`{"name": "Dmitriy", "surname": "Ushakov"}`)}
+
+	if err := b.Publish(ctx, "FIO", msg); err != nil {
+		t.Fatalf("can't publish message: %s", err)
+	}
+
+	transport.Run()
+
+	if err := b.WaitCommitted(ctx, 0); err != nil {
+		t.Fatalf("message isn't committed: %s", err)
+	}
+
+	if err := transport.Shutdown(ctx); err != nil {
+		t.Fatalf("can't shutdown: %s", err)
+	}
+
+	if len(useCase.created) != 1 {
+		t.Errorf("created %d users, want 1", len(useCase.created))
+	}
+}
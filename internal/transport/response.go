@@ -10,7 +10,17 @@ func Response(
 	data interface{},
 ) {
 
+	ResponseStatus(w, http.StatusOK, data)
+}
+
+func ResponseStatus(
+	w http.ResponseWriter,
+	statusCode int,
+	data interface{},
+) {
+
 	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
 
 	if err := json.NewEncoder(w).Encode(&data); err != nil {
 		Error(
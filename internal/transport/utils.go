@@ -1,9 +1,15 @@
 package transport
 
 import (
+	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 
+	"github.com/jackvonhouse/enrichment/internal/dto"
 	"github.com/jackvonhouse/enrichment/internal/errors"
 	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
 )
@@ -23,6 +29,11 @@ var DefaultErrorHttpCodes = map[uint32]int{
 	errors.ErrCantEnrichment.TypeId: http.StatusInternalServerError,
 	errors.ErrAlreadyExists.TypeId:  http.StatusConflict,
 	errors.ErrNotFound.TypeId:       http.StatusNotFound,
+	errors.ErrCircuitOpen.TypeId:    http.StatusServiceUnavailable,
+	errors.ErrQuotaExhausted.TypeId: http.StatusServiceUnavailable,
+	errors.ErrRateLimited.TypeId:    http.StatusServiceUnavailable,
+	errors.ErrUnknownName.TypeId:    http.StatusUnprocessableEntity,
+	errors.ErrInvalidValue.TypeId:   http.StatusBadRequest,
 }
 
 func ErrorToHttpResponse(
@@ -44,6 +55,149 @@ func ErrorToHttpResponse(
 	return code, err.Error()
 }
 
+// SetRetryAfter выставляет заголовок Retry-After, если ошибка
+// сообщает, когда запрос можно повторить
+func SetRetryAfter(
+	w http.ResponseWriter,
+	err error,
+) {
+
+	var retryAfter errors.RetryAfter
+
+	if !errpkg.As(err, &retryAfter) || retryAfter.Delay <= 0 {
+		return
+	}
+
+	seconds := int(math.Ceil(retryAfter.Delay.Seconds()))
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
+// FilterFromQuery разбирает параметры фильтрации пользователей
+func FilterFromQuery(
+	queries url.Values,
+) dto.FilterDTO {
+
+	age, err := StringToInt(queries.Get("age"))
+	if err != nil || age < 0 {
+		age = 0
+	}
+
+	return dto.FilterDTO{
+		Name:       queries.Get("name"),
+		Surname:    queries.Get("surname"),
+		Patronymic: queries.Get("patronymic"),
+		Age:        age,
+		AgeSort:    queries.Get("age_sort_operator"),
+		Gender:     queries["gender"],
+		Country:    queries["country"],
+		Conditions: conditionsFromQuery(queries),
+	}
+}
+
+// conditionsFromQuery разбирает параметры вида поле[оператор]=значение:
+// ?age[between]=20,35&surname[prefix]=Ush&country[not_in]=RU,KZ.
+// Поля и операторы проверяет репозиторий
+func conditionsFromQuery(
+	queries url.Values,
+) []dto.Condition {
+
+	keys := make([]string, 0, len(queries))
+	for key := range queries {
+		keys = append(keys, key)
+	}
+
+	// Порядок условий не должен зависеть от обхода map
+	slices.Sort(keys)
+
+	var conditions []dto.Condition
+
+	for _, key := range keys {
+		field, operator, ok := strings.Cut(key, "[")
+		if !ok || !strings.HasSuffix(operator, "]") {
+			continue
+		}
+
+		condition := dto.Condition{
+			Field:    field,
+			Operator: strings.TrimSuffix(operator, "]"),
+		}
+
+		for _, value := range queries[key] {
+			switch condition.Operator {
+			case dto.OpIn, dto.OpNotIn, dto.OpBetween:
+				condition.Values = append(condition.Values, strings.Split(value, ",")...)
+			default:
+				condition.Values = append(condition.Values, value)
+			}
+		}
+
+		conditions = append(conditions, condition)
+	}
+
+	return conditions
+}
+
+// Links - ссылки на соседние страницы выборки, nil - такой страницы нет
+type Links struct {
+	Self  string  `json:"self"`
+	First string  `json:"first"`
+	Prev  *string `json:"prev"`
+	Next  *string `json:"next"`
+	Last  *string `json:"last"`
+}
+
+// OffsetLinks строит ссылки, меняя offset в адресе текущего запроса;
+// без total следующая страница предполагается, если текущая заполнена
+func OffsetLinks(
+	u *url.URL,
+	limit int,
+	offset int,
+	count int,
+	total *int,
+) Links {
+
+	link := func(offset int) *string {
+		queries := u.Query()
+		queries.Set("offset", strconv.Itoa(offset))
+
+		link := fmt.Sprintf("%s?%s", u.Path, queries.Encode())
+
+		return &link
+	}
+
+	links := Links{
+		Self:  *link(offset),
+		First: *link(0),
+	}
+
+	if limit <= 0 {
+		return links
+	}
+
+	if offset > 0 {
+		links.Prev = link(max(offset-limit, 0))
+	}
+
+	if total == nil {
+		if count == limit {
+			links.Next = link(offset + limit)
+		}
+
+		return links
+	}
+
+	if offset+limit < *total {
+		links.Next = link(offset + limit)
+	}
+
+	if *total > 0 {
+		links.Last = link((*total - 1) / limit * limit)
+	}
+
+	return links
+}
+
 var defaultSortFields = map[string]bool{
 	"id":         true,
 	"name":       true,
@@ -68,3 +222,70 @@ func IsSortOrder(value string) bool {
 	_, ok := defaultSortOrders[value]
 	return ok
 }
+
+// DefaultSort - порядок выборки, если клиент его не задал
+var DefaultSort = dto.SortDTO{
+	Keys: []dto.SortKey{{Field: "id", Order: dto.SortDesc}},
+}
+
+// SortFromQuery разбирает параметр sort вида
+// country:asc,age:desc:nulls_last. Поля и направления проверяет
+// репозиторий. Без sort используются устаревшие sort_by и sort_order
+func SortFromQuery(
+	queries url.Values,
+) dto.SortDTO {
+
+	if !queries.Has("sort") {
+		sortBy := queries.Get("sort_by")
+		if !IsSortField(sortBy) {
+			return DefaultSort
+		}
+
+		sortOrder := queries.Get("sort_order")
+		if !IsSortOrder(sortOrder) {
+			sortOrder = dto.SortDesc
+		}
+
+		return dto.SortDTO{
+			Keys: []dto.SortKey{{Field: sortBy, Order: sortOrder}},
+		}
+	}
+
+	items := strings.Split(queries.Get("sort"), ",")
+	sort := dto.SortDTO{
+		Keys: make([]dto.SortKey, len(items)),
+	}
+
+	for i, item := range items {
+		parts := strings.SplitN(strings.TrimSpace(item), ":", 3)
+
+		key := dto.SortKey{Field: parts[0]}
+
+		if len(parts) > 1 {
+			key.Order = parts[1]
+		}
+
+		if len(parts) > 2 {
+			key.Nulls = strings.TrimPrefix(parts[2], "nulls_")
+		}
+
+		sort.Keys[i] = key
+	}
+
+	return sort
+}
+
+// IsCountryCode проверяет, что значение похоже на код страны ISO 3166-1 alpha-2
+func IsCountryCode(value string) bool {
+	if len(value) != 2 {
+		return false
+	}
+
+	for _, c := range value {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,163 @@
+package admin
+
+import (
+	"context"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+	"sync"
+	"time"
+)
+
+type serviceEnrichment interface {
+	Quota() []dto.ProviderQuota
+	Exhausted() error
+}
+
+type useCaseUser interface {
+	Reenrich(context.Context, dto.ReenrichDTO, func(dto.ReenrichReport)) (dto.ReenrichReport, error)
+}
+
+// reenrichJob - последнее запущенное повторное обогащение;
+// одновременно выполняется не более одного
+type reenrichJob struct {
+	mu     *sync.Mutex
+	report *dto.ReenrichReport
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type UseCase struct {
+	enrichment serviceEnrichment
+	user       useCaseUser
+
+	reenrich *reenrichJob
+
+	logger log.Logger
+}
+
+func New(
+	enrichment serviceEnrichment,
+	user useCaseUser,
+	logger log.Logger,
+) UseCase {
+
+	return UseCase{
+		enrichment: enrichment,
+		user:       user,
+		reenrich:   &reenrichJob{mu: &sync.Mutex{}},
+		logger:     logger.WithField("unit", "admin"),
+	}
+}
+
+func (u UseCase) Quota(
+	_ context.Context,
+) []dto.ProviderQuota {
+
+	return u.enrichment.Quota()
+}
+
+// StartReenrich запускает повторное обогащение в фоне,
+// ход выполнения доступен через ReenrichStatus
+func (u UseCase) StartReenrich(
+	_ context.Context,
+	data dto.ReenrichDTO,
+) (dto.ReenrichReport, error) {
+
+	job := u.reenrich
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	if job.report != nil && job.report.Status == dto.ReenrichRunning {
+		return dto.ReenrichReport{}, errors.
+			ErrAlreadyExists.
+			New("re-enrichment is already running")
+	}
+
+	// Без квоты задача лишь пометит всех пользователей неудачными
+	if err := u.enrichment.Exhausted(); err != nil {
+		return dto.ReenrichReport{}, err
+	}
+
+	// Задача переживает запрос, который её запустил
+	ctx, cancel := context.WithCancel(context.Background())
+
+	report := dto.ReenrichReport{
+		Status:    dto.ReenrichRunning,
+		DryRun:    data.DryRun,
+		StartedAt: time.Now(),
+	}
+
+	job.report = &report
+	job.cancel = cancel
+	job.done = make(chan struct{})
+
+	go func(done chan struct{}) {
+		defer close(done)
+		defer cancel()
+
+		final, _ := u.user.Reenrich(ctx, data, job.update)
+
+		job.update(final)
+	}(job.done)
+
+	return report, nil
+}
+
+func (u UseCase) ReenrichStatus(
+	_ context.Context,
+) (dto.ReenrichReport, error) {
+
+	job := u.reenrich
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	if job.report == nil {
+		return dto.ReenrichReport{}, errors.
+			ErrNotFound.
+			New("re-enrichment hasn't been started")
+	}
+
+	return *job.report, nil
+}
+
+// Shutdown прерывает выполняющееся повторное обогащение
+func (u UseCase) Shutdown(
+	ctx context.Context,
+) error {
+
+	job := u.reenrich
+
+	job.mu.Lock()
+
+	if job.cancel == nil {
+		job.mu.Unlock()
+
+		return nil
+	}
+
+	job.cancel()
+	done := job.done
+
+	job.mu.Unlock()
+
+	select {
+	case <-done:
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (j *reenrichJob) update(
+	report dto.ReenrichReport,
+) {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.report = &report
+}
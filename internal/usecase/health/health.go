@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+const (
+	StatusOk       = "ok"
+	StatusDegraded = "degraded"
+)
+
+type serviceEnrichment interface {
+	Health() []dto.ProviderHealth
+	CacheStats() []dto.CacheStats
+	CoalescingStats() []dto.CoalescingStats
+}
+
+type UseCase struct {
+	enrichment serviceEnrichment
+
+	logger log.Logger
+}
+
+func New(
+	enrichment serviceEnrichment,
+	logger log.Logger,
+) UseCase {
+
+	return UseCase{
+		enrichment: enrichment,
+		logger:     logger.WithField("unit", "health"),
+	}
+}
+
+func (u UseCase) Check(
+	_ context.Context,
+) dto.Health {
+
+	health := dto.Health{
+		Status:     StatusOk,
+		Providers:  u.enrichment.Health(),
+		Cache:      u.enrichment.CacheStats(),
+		Coalescing: u.enrichment.CoalescingStats(),
+	}
+
+	for _, provider := range health.Providers {
+		if provider.State != "closed" {
+			health.Status = StatusDegraded
+		}
+	}
+
+	return health
+}
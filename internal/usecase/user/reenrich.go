@@ -0,0 +1,229 @@
+package user
+
+import (
+	"context"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"time"
+)
+
+const defaultReenrichBatchSize = 100
+
+// Reenrich заново обогащает пользователей, подходящих под фильтр,
+// порциями по возрастанию id; каждая порция запрашивается у провайдеров
+// пакетно. Атрибуты, заданные вручную, не меняются. progress вызывается
+// после каждой порции
+func (u UseCase) Reenrich(
+	ctx context.Context,
+	data dto.ReenrichDTO,
+	progress func(dto.ReenrichReport),
+) (dto.ReenrichReport, error) {
+
+	batchSize := data.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultReenrichBatchSize
+	}
+
+	report := dto.ReenrichReport{
+		Status:    dto.ReenrichRunning,
+		DryRun:    data.DryRun,
+		StartedAt: time.Now(),
+	}
+
+	// Смысл повторного обогащения - в свежих данных провайдеров
+	ctx = u.enrichment.BypassCache(ctx)
+
+	filter := data.Filter
+	sort := dto.SortDTO{
+		Keys: []dto.SortKey{{Field: "id", Order: dto.SortAsc}},
+	}
+
+	for {
+		list, err := u.service.Get(ctx, dto.GetDTO{Limit: batchSize}, filter, sort)
+		if err != nil {
+			return u.finishReenrich(report, err), err
+		}
+
+		users := list.Items
+
+		if len(users) == 0 {
+			return u.finishReenrich(report, nil), nil
+		}
+
+		enrichments, failures := u.enrichBatch(ctx, users)
+
+		if ctx.Err() != nil {
+			return u.finishReenrich(report, ctx.Err()), ctx.Err()
+		}
+
+		for _, user := range users {
+			err := u.reenrichUser(
+				ctx, user, enrichments[user.ID], failures[user.ID], data.DryRun, &report,
+			)
+			if err != nil {
+				return u.finishReenrich(report, err), err
+			}
+		}
+
+		filter.AfterID = users[len(users)-1].ID
+
+		u.logger.Infof(
+			"re-enrichment progress: processed %d, updated %d, failed %d",
+			report.Processed, report.Updated, report.Failed,
+		)
+
+		if progress != nil {
+			progress(report)
+		}
+	}
+}
+
+// reenrichUser возвращает ошибку, только если продолжать
+// обработку бессмысленно
+func (u UseCase) reenrichUser(
+	ctx context.Context,
+	user dto.User,
+	enrichment dto.EnrichmentDTO,
+	err error,
+	dryRun bool,
+	report *dto.ReenrichReport,
+) error {
+
+	logger := u.logger.WithField("user_id", user.ID)
+
+	report.Processed++
+
+	if err != nil {
+		logger.Warnf("can't re-enrich user: %s", err)
+		report.Failed++
+
+		return nil
+	}
+
+	attributes := make([]string, 0, 3)
+
+	if diffAttribute(&report.Age, user.AgeSource, ageChanged(user, enrichment.Age)) {
+		attributes = append(attributes, "age")
+	}
+
+	if diffAttribute(&report.Gender, user.GenderSource, genderChanged(user, enrichment.Gender)) {
+		attributes = append(attributes, "gender")
+	}
+
+	if diffAttribute(&report.Country, user.CountrySource, countryChanged(user, enrichment.Country)) {
+		attributes = append(attributes, "country")
+	}
+
+	// Успешное обогащение снимает ошибку прошлой попытки,
+	// даже если значения не изменились
+	if len(attributes) == 0 && user.EnrichmentStatus == dto.EnrichmentDone {
+		report.Unchanged++
+
+		return nil
+	}
+
+	if !dryRun {
+		if err := u.service.UpdateEnrichment(ctx, user.ID, enrichment, attributes); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			logger.Warnf("can't save re-enrichment: %s", err)
+			report.Failed++
+
+			return nil
+		}
+	}
+
+	report.Updated++
+
+	return nil
+}
+
+func (u UseCase) finishReenrich(
+	report dto.ReenrichReport,
+	err error,
+) dto.ReenrichReport {
+
+	finishedAt := time.Now()
+
+	report.FinishedAt = &finishedAt
+	report.Status = dto.ReenrichDone
+
+	if err != nil {
+		report.Status = dto.ReenrichFailed
+		report.Error = err.Error()
+
+		u.logger.Warnf("re-enrichment failed: %s", err)
+
+		return report
+	}
+
+	u.logger.Infof(
+		"re-enrichment done: processed %d, updated %d, unchanged %d, failed %d",
+		report.Processed, report.Updated, report.Unchanged, report.Failed,
+	)
+
+	return report
+}
+
+// diffAttribute учитывает атрибут в отчёте и сообщает,
+// нужно ли его перезаписать
+func diffAttribute(
+	diff *dto.ReenrichDiff,
+	source *string,
+	changed bool,
+) bool {
+
+	switch {
+	case source != nil && *source == dto.SourceManual:
+		diff.Manual++
+
+		return false
+
+	case changed:
+		diff.Changed++
+
+		return true
+
+	default:
+		diff.Unchanged++
+
+		return false
+	}
+}
+
+func ageChanged(user dto.User, age *dto.AgeDTO) bool {
+	if age == nil {
+		return user.Age != nil
+	}
+
+	return differs(user.Age, age.Age) ||
+		differs(user.AgeCount, age.Count) ||
+		differs(user.AgeSource, age.Source)
+}
+
+func genderChanged(user dto.User, gender *dto.GenderDTO) bool {
+	if gender == nil {
+		return user.Gender != nil
+	}
+
+	return differs(user.Gender, gender.Gender) ||
+		differs(user.GenderProbability, gender.Probability) ||
+		differs(user.GenderCount, gender.Count) ||
+		differs(user.GenderSource, gender.Source)
+}
+
+func countryChanged(user dto.User, country *dto.CountryDTO) bool {
+	if country == nil {
+		return user.Country != nil
+	}
+
+	return differs(user.Country, country.Country) ||
+		differs(user.CountryProbability, country.Probability) ||
+		differs(user.CountryCount, country.Count) ||
+		differs(user.CountrySource, country.Source)
+}
+
+func differs[T comparable](current *T, value T) bool {
+	return current == nil || *current != value
+}
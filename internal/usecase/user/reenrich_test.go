@@ -0,0 +1,220 @@
+package user
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+// serviceStub записывает атрибуты, переданные в UpdateEnrichment;
+// остальные методы сервиса в тестах не вызываются
+type serviceStub struct {
+	serviceUser
+
+	updated [][]string
+	err     error
+}
+
+func (s *serviceStub) UpdateEnrichment(
+	_ context.Context,
+	_ int,
+	_ dto.EnrichmentDTO,
+	attributes []string,
+) error {
+
+	s.updated = append(s.updated, attributes)
+
+	return s.err
+}
+
+func ref[T any](value T) *T {
+	return &value
+}
+
+func testReenrichUser() dto.User {
+	return dto.User{
+		ID:                1,
+		Name:              "Dmitriy",
+		Age:               ref(43),
+		AgeCount:          ref(100),
+		AgeSource:         ref("agify"),
+		Gender:            ref("male"),
+		GenderProbability: ref(0.99),
+		GenderCount:       ref(100),
+		GenderSource:      ref("genderize"),
+		EnrichmentStatus:  dto.EnrichmentDone,
+	}
+}
+
+func testReenrichEnrichment() dto.EnrichmentDTO {
+	return dto.EnrichmentDTO{
+		Age:    &dto.AgeDTO{Age: 43, Count: 100, Source: "agify"},
+		Gender: &dto.GenderDTO{Gender: "male", Probability: 0.99, Count: 100, Source: "genderize"},
+	}
+}
+
+func TestReenrichUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       func(*dto.User)
+		enrichment func(*dto.EnrichmentDTO)
+		err        error
+		updateErr  error
+		dryRun     bool
+		updated    [][]string
+		report     dto.ReenrichReport
+	}{
+		{
+			name: "nothing changed",
+			report: dto.ReenrichReport{
+				Processed: 1, Unchanged: 1,
+				Age:     dto.ReenrichDiff{Unchanged: 1},
+				Gender:  dto.ReenrichDiff{Unchanged: 1},
+				Country: dto.ReenrichDiff{Unchanged: 1},
+			},
+		},
+		{
+			name:       "changed value",
+			enrichment: func(e *dto.EnrichmentDTO) { e.Age.Age = 44 },
+			updated:    [][]string{{"age"}},
+			report: dto.ReenrichReport{
+				Processed: 1, Updated: 1,
+				Age:     dto.ReenrichDiff{Changed: 1},
+				Gender:  dto.ReenrichDiff{Unchanged: 1},
+				Country: dto.ReenrichDiff{Unchanged: 1},
+			},
+		},
+		{
+			name:       "changed source",
+			enrichment: func(e *dto.EnrichmentDTO) { e.Gender.Source = dto.SourcePatronymic },
+			updated:    [][]string{{"gender"}},
+			report: dto.ReenrichReport{
+				Processed: 1, Updated: 1,
+				Age:     dto.ReenrichDiff{Unchanged: 1},
+				Gender:  dto.ReenrichDiff{Changed: 1},
+				Country: dto.ReenrichDiff{Unchanged: 1},
+			},
+		},
+		{
+			name:       "value became unknown",
+			enrichment: func(e *dto.EnrichmentDTO) { e.Age = nil },
+			updated:    [][]string{{"age"}},
+			report: dto.ReenrichReport{
+				Processed: 1, Updated: 1,
+				Age:     dto.ReenrichDiff{Changed: 1},
+				Gender:  dto.ReenrichDiff{Unchanged: 1},
+				Country: dto.ReenrichDiff{Unchanged: 1},
+			},
+		},
+		{
+			name: "manual attribute is kept",
+			user: func(u *dto.User) { u.AgeSource = ref(dto.SourceManual) },
+			enrichment: func(e *dto.EnrichmentDTO) {
+				e.Age.Age = 50
+				e.Country = &dto.CountryDTO{Country: "RU", Probability: 0.6, Count: 100, Source: "nationalize"}
+			},
+			updated: [][]string{{"country"}},
+			report: dto.ReenrichReport{
+				Processed: 1, Updated: 1,
+				Age:     dto.ReenrichDiff{Manual: 1},
+				Gender:  dto.ReenrichDiff{Unchanged: 1},
+				Country: dto.ReenrichDiff{Changed: 1},
+			},
+		},
+		{
+			name: "only manual changes",
+			user: func(u *dto.User) {
+				u.AgeSource = ref(dto.SourceManual)
+				u.GenderSource = ref(dto.SourceManual)
+			},
+			enrichment: func(e *dto.EnrichmentDTO) {
+				e.Age.Age = 50
+				e.Gender.Gender = "female"
+			},
+			report: dto.ReenrichReport{
+				Processed: 1, Unchanged: 1,
+				Age:     dto.ReenrichDiff{Manual: 1},
+				Gender:  dto.ReenrichDiff{Manual: 1},
+				Country: dto.ReenrichDiff{Unchanged: 1},
+			},
+		},
+		{
+			// Ошибка прошлой попытки снимается без изменения атрибутов
+			name:    "failed user is completed",
+			user:    func(u *dto.User) { u.EnrichmentStatus = dto.EnrichmentFailed },
+			updated: [][]string{{}},
+			report: dto.ReenrichReport{
+				Processed: 1, Updated: 1,
+				Age:     dto.ReenrichDiff{Unchanged: 1},
+				Gender:  dto.ReenrichDiff{Unchanged: 1},
+				Country: dto.ReenrichDiff{Unchanged: 1},
+			},
+		},
+		{
+			name:       "dry run doesn't save",
+			enrichment: func(e *dto.EnrichmentDTO) { e.Age.Age = 44 },
+			dryRun:     true,
+			report: dto.ReenrichReport{
+				Processed: 1, Updated: 1,
+				Age:     dto.ReenrichDiff{Changed: 1},
+				Gender:  dto.ReenrichDiff{Unchanged: 1},
+				Country: dto.ReenrichDiff{Unchanged: 1},
+			},
+		},
+		{
+			name:   "enrichment failure",
+			err:    errors.ErrCircuitOpen.New("circuit open"),
+			report: dto.ReenrichReport{Processed: 1, Failed: 1},
+		},
+		{
+			name:       "save failure",
+			enrichment: func(e *dto.EnrichmentDTO) { e.Age.Age = 44 },
+			updateErr:  errors.ErrInternal.New("can't update user"),
+			updated:    [][]string{{"age"}},
+			report: dto.ReenrichReport{
+				Processed: 1, Failed: 1,
+				Age:     dto.ReenrichDiff{Changed: 1},
+				Gender:  dto.ReenrichDiff{Unchanged: 1},
+				Country: dto.ReenrichDiff{Unchanged: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, enrichment := testReenrichUser(), testReenrichEnrichment()
+
+			if tt.user != nil {
+				tt.user(&user)
+			}
+
+			if tt.enrichment != nil {
+				tt.enrichment(&enrichment)
+			}
+
+			service := &serviceStub{err: tt.updateErr}
+
+			u := New(nil, service, config.EnrichmentWorker{}, log.NewLogrusLogger())
+
+			report := dto.ReenrichReport{}
+
+			err := u.reenrichUser(context.Background(), user, enrichment, tt.err, tt.dryRun, &report)
+			if err != nil {
+				t.Fatalf("reenrichUser() error: %s", err)
+			}
+
+			if !reflect.DeepEqual(report, tt.report) {
+				t.Errorf("report = %+v, want %+v", report, tt.report)
+			}
+
+			if !reflect.DeepEqual(service.updated, tt.updated) {
+				t.Errorf("updated attributes = %q, want %q", service.updated, tt.updated)
+			}
+		})
+	}
+}
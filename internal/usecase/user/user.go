@@ -2,14 +2,30 @@ package user
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
+	"github.com/jackvonhouse/enrichment/config"
 	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
 	"github.com/jackvonhouse/enrichment/pkg/log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 type serviceUser interface {
-	Create(context.Context, dto.CreateDTO, dto.EnrichmentDTO) (int, error)
+	Create(context.Context, dto.CreateDTO) (int, error)
 
-	Get(context.Context, dto.GetDTO, dto.FilterDTO, dto.SortDTO) ([]dto.User, error)
+	ClaimPending(context.Context, int, time.Duration) ([]dto.User, error)
+	CompleteEnrichment(context.Context, int, int, dto.EnrichmentDTO) error
+	FailEnrichment(context.Context, int, int, string, *time.Time) error
+	UpdateEnrichment(context.Context, int, dto.EnrichmentDTO, []string) error
+
+	Get(context.Context, dto.GetDTO, dto.FilterDTO, dto.SortDTO) (dto.UserList, error)
+	GetPage(context.Context, dto.PageDTO, dto.FilterDTO, dto.SortDTO) (dto.UserPage, error)
 	GetById(context.Context, int) (dto.User, error)
 
 	Update(context.Context, dto.UpdateDTO) (int, error)
@@ -18,58 +34,407 @@ type serviceUser interface {
 }
 
 type serviceEnrichment interface {
-	Agify(string) (int, error)
-	Genderize(string) (string, error)
-	Nationalize(string) (string, error)
+	AgifyBatch(context.Context, []string, string) (map[string]*dto.AgeDTO, error)
+	GenderizeBatch(context.Context, []string, string) (map[string]*dto.GenderDTO, error)
+	NationalizeBatch(context.Context, []string) (map[string]*dto.CountryDTO, error)
+
+	InferGender(string) (*dto.GenderDTO, bool)
+
+	// BypassCache заставляет запрашивать значения у провайдеров, минуя кеш
+	BypassCache(context.Context) context.Context
 }
 
+var errSiblingFailed = stderrors.New("sibling enrichment failed")
+
 type UseCase struct {
 	enrichment serviceEnrichment
 	service    serviceUser
 
+	worker config.EnrichmentWorker
+
 	logger log.Logger
 }
 
 func New(
 	enrichment serviceEnrichment,
 	service serviceUser,
+	worker config.EnrichmentWorker,
 	logger log.Logger,
 ) UseCase {
 
 	return UseCase{
 		enrichment: enrichment,
 		service:    service,
+		worker:     worker,
 		logger:     logger.WithField("unit", "enrichment"),
 	}
 }
 
+// Create сохраняет пользователя в статусе pending,
+// обогащение выполняется фоновыми обработчиками
 func (u UseCase) Create(
 	ctx context.Context,
 	data dto.CreateDTO,
 ) (int, error) {
 
-	age, err := u.enrichment.Agify(data.Name)
+	data.CountryHint = strings.ToUpper(data.CountryHint)
+
+	return u.service.Create(ctx, data)
+}
+
+// EnrichPending обогащает очередную порцию ожидающих пользователей
+// и возвращает их количество
+func (u UseCase) EnrichPending(
+	ctx context.Context,
+	limit int,
+) (int, error) {
+
+	users, err := u.service.ClaimPending(ctx, limit, u.worker.Lease)
 	if err != nil {
 		return 0, err
 	}
 
-	gender, err := u.enrichment.Genderize(data.Name)
-	if err != nil {
-		return 0, err
+	enrichments, failures := u.enrichBatch(ctx, users)
+
+	for _, user := range users {
+		logger := u.logger.WithField("user_id", user.ID)
+
+		enrichment, err := enrichments[user.ID], failures[user.ID]
+		if err == nil {
+			err = u.service.CompleteEnrichment(ctx, user.ID, user.EnrichmentLease, enrichment)
+		}
+
+		if err == nil {
+			continue
+		}
+
+		// Пользователя уже забрал другой обработчик
+		if errpkg.Has(err, errors.ErrLeaseLost) {
+			logger.Warn(err)
+
+			continue
+		}
+
+		nextAttemptAt := u.nextAttempt(user.EnrichmentAttempts, err)
+
+		logger.Warnf("can't enrich user (next attempt at %v): %s", nextAttemptAt, err)
+
+		if err := u.service.FailEnrichment(
+			ctx, user.ID, user.EnrichmentLease, err.Error(), nextAttemptAt,
+		); err != nil {
+			logger.Warnf("can't save enrichment failure: %s", err)
+		}
 	}
 
-	country, err := u.enrichment.Nationalize(data.Name)
-	if err != nil {
-		return 0, err
+	return len(users), nil
+}
+
+// nextAttempt возвращает nil, если повторять обогащение бессмысленно
+func (u UseCase) nextAttempt(
+	attempts int,
+	err error,
+) *time.Time {
+
+	if attempts+1 >= u.worker.MaxAttempts {
+		return nil
+	}
+
+	if errpkg.Has(err, errors.ErrUnknownName) ||
+		errpkg.Has(err, errors.ErrAlreadyExists) {
+
+		return nil
+	}
+
+	nextAttemptAt := time.Now().Add(u.worker.RetryDelay << attempts)
+
+	return &nextAttemptAt
+}
+
+// enrichBatch обогащает пользователей пакетными запросами: страна
+// запрашивается для всех имён разом, возраст и пол - для групп
+// пользователей с одной страной. Группы по подсказке страны не ждут
+// определения страны, все группы обогащаются параллельно. Для каждого
+// пользователя возвращается либо результат, либо ошибка
+func (u UseCase) enrichBatch(
+	ctx context.Context,
+	users []dto.User,
+) (map[int]dto.EnrichmentDTO, map[int]error) {
+
+	enrichments := make(map[int]dto.EnrichmentDTO, len(users))
+	failures := make(map[int]error)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	lookup := func(groups map[string]*batchGroup) {
+		for _, group := range groups {
+			wg.Add(1)
+
+			go func(group *batchGroup) {
+				defer wg.Done()
+
+				u.lookupGroup(ctx, group)
+			}(group)
+		}
+	}
+
+	names := make([]string, len(users))
+	for i, user := range users {
+		names[i] = user.Name
+	}
+
+	// Подсказка точнее определённой страны, поэтому возраст и пол
+	// по ней запрашиваются одновременно со страной
+	hinted := make(map[string]*batchGroup)
+	detect := make([]dto.User, 0, len(users))
+
+	for _, user := range users {
+		if user.CountryHint == nil {
+			detect = append(detect, user)
+
+			continue
+		}
+
+		addToGroup(hinted, *user.CountryHint, user)
+	}
+
+	lookup(hinted)
+
+	var countries map[string]*dto.CountryDTO
+
+	if err := u.fanOut(ctx, names, map[string]func(context.Context) error{
+		"nationalize": func(ctx context.Context) (err error) {
+			countries, err = u.enrichment.NationalizeBatch(ctx, names)
+			return err
+		},
+	}); err != nil {
+		cancel()
+		wg.Wait()
+
+		for _, user := range users {
+			failures[user.ID] = err
+		}
+
+		return enrichments, failures
+	}
+
+	// Значение страны по умолчанию подсказкой не служит
+	detected := make(map[string]*batchGroup)
+
+	for _, user := range detect {
+		country, ok := countries[user.Name]
+		if !ok {
+			continue
+		}
+
+		var countryID string
+
+		if country != nil && country.Source != dto.SourceDefault {
+			countryID = country.Country
+		}
+
+		addToGroup(detected, countryID, user)
+	}
+
+	lookup(detected)
+
+	wg.Wait()
+
+	for _, user := range detect {
+		if _, ok := countries[user.Name]; !ok {
+			failures[user.ID] = unknownName("nationalize")
+		}
+	}
+
+	for _, groups := range []map[string]*batchGroup{hinted, detected} {
+		for _, group := range groups {
+			for _, user := range group.users {
+				if _, ok := countries[user.Name]; !ok {
+					failures[user.ID] = unknownName("nationalize")
+
+					continue
+				}
+
+				if group.err != nil {
+					failures[user.ID] = group.err
+
+					continue
+				}
+
+				age, ok := group.ages[user.Name]
+				if !ok {
+					failures[user.ID] = unknownName("agify")
+
+					continue
+				}
+
+				gender, ok := group.inferred[user.ID]
+				if !ok {
+					if gender, ok = group.genders[user.Name]; !ok {
+						failures[user.ID] = unknownName("genderize")
+
+						continue
+					}
+				}
+
+				enrichments[user.ID] = dto.EnrichmentDTO{
+					Age:     age,
+					Gender:  gender,
+					Country: countries[user.Name],
+				}
+			}
+		}
+	}
+
+	return enrichments, failures
+}
+
+// batchGroup - пользователи с одной страной и полученные для них значения
+type batchGroup struct {
+	countryID string
+	users     []dto.User
+
+	ages     map[string]*dto.AgeDTO
+	genders  map[string]*dto.GenderDTO
+	inferred map[int]*dto.GenderDTO
+	err      error
+}
+
+func addToGroup(
+	groups map[string]*batchGroup,
+	countryID string,
+	user dto.User,
+) {
+
+	group, ok := groups[countryID]
+	if !ok {
+		group = &batchGroup{countryID: countryID}
+		groups[countryID] = group
+	}
+
+	group.users = append(group.users, user)
+}
+
+// lookupGroup запрашивает возраст и пол для группы
+func (u UseCase) lookupGroup(
+	ctx context.Context,
+	group *batchGroup,
+) {
+
+	names := make([]string, 0, len(group.users))
+	genderNames := make([]string, 0, len(group.users))
+
+	group.inferred = make(map[int]*dto.GenderDTO)
+
+	for _, user := range group.users {
+		names = append(names, user.Name)
+
+		// Отчество определяет пол точнее провайдера
+		if gender, ok := u.enrichment.InferGender(user.Patronymic); ok {
+			group.inferred[user.ID] = gender
+
+			continue
+		}
+
+		genderNames = append(genderNames, user.Name)
+	}
+
+	lookups := map[string]func(context.Context) error{
+		"agify": func(ctx context.Context) (err error) {
+			group.ages, err = u.enrichment.AgifyBatch(ctx, names, group.countryID)
+			return err
+		},
+	}
+
+	if len(genderNames) > 0 {
+		lookups["genderize"] = func(ctx context.Context) (err error) {
+			group.genders, err = u.enrichment.GenderizeBatch(ctx, genderNames, group.countryID)
+			return err
+		}
+	}
+
+	group.err = u.fanOut(ctx, names, lookups)
+}
+
+// unknownName - ошибка для имени, по которому провайдер не дал
+// значения, проходящего политику атрибута
+func unknownName(provider string) error {
+	return errors.
+		ErrUnknownName.
+		New(fmt.Sprintf("can't enrich user: %s has no confident value", provider))
+}
+
+// fanOut выполняет запросы к провайдерам параллельно; ошибка одного
+// из них отменяет остальные
+func (u UseCase) fanOut(
+	ctx context.Context,
+	names []string,
+	lookups map[string]func(context.Context) error,
+) error {
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures = map[string]error{}
+	)
+
+	for provider, lookup := range lookups {
+		wg.Add(1)
+
+		go func(provider string, lookup func(context.Context) error) {
+			defer wg.Done()
+
+			err := lookup(ctx)
+			if err == nil {
+				return
+			}
+
+			// Запрос, отменённый из-за ошибки соседнего провайдера,
+			// не считается самостоятельной ошибкой
+			if errpkg.Is(err, context.Canceled) &&
+				context.Cause(ctx) == errSiblingFailed {
+
+				return
+			}
+
+			mu.Lock()
+			failures[provider] = err
+			mu.Unlock()
+
+			cancel(errSiblingFailed)
+		}(provider, lookup)
+	}
+
+	wg.Wait()
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	providers := make([]string, 0, len(failures))
+	for provider := range failures {
+		providers = append(providers, provider)
 	}
 
-	enrichment := dto.EnrichmentDTO{
-		Age:     age,
-		Gender:  gender,
-		Country: country,
+	sort.Strings(providers)
+
+	errs := make([]error, 0, len(providers))
+	for _, provider := range providers {
+		errs = append(errs, fmt.Errorf("%s: %w", provider, failures[provider]))
 	}
 
-	return u.service.Create(ctx, data, enrichment)
+	u.logger.Warnf("can't enrich %q: %s", names, stderrors.Join(errs...))
+
+	errType := failureType(failures)
+
+	return errType.
+		New(fmt.Sprintf("can't enrich user: %s failed", strings.Join(providers, ", "))).
+		Wrap(stderrors.Join(errs...))
 }
 
 func (u UseCase) Get(
@@ -77,11 +442,21 @@ func (u UseCase) Get(
 	data dto.GetDTO,
 	filter dto.FilterDTO,
 	sort dto.SortDTO,
-) ([]dto.User, error) {
+) (dto.UserList, error) {
 
 	return u.service.Get(ctx, data, filter, sort)
 }
 
+func (u UseCase) GetPage(
+	ctx context.Context,
+	page dto.PageDTO,
+	filter dto.FilterDTO,
+	sort dto.SortDTO,
+) (dto.UserPage, error) {
+
+	return u.service.GetPage(ctx, page, filter, sort)
+}
+
 func (u UseCase) GetById(
 	ctx context.Context,
 	id int,
@@ -105,3 +480,54 @@ func (u UseCase) Delete(
 
 	return u.service.Delete(ctx, id)
 }
+
+// failurePriority - типы итоговой ошибки по убыванию важности:
+// недоступность провайдера важнее сбоя, а сбой важнее неизвестного имени
+var failurePriority = []*errpkg.Type{
+	errors.ErrCircuitOpen,
+	errors.ErrQuotaExhausted,
+	errors.ErrRateLimited,
+	errors.ErrCantEnrichment,
+	errors.ErrUnknownName,
+}
+
+// failureType выбирает самый важный тип среди ошибок провайдеров,
+// поэтому результат не зависит от порядка обхода failures
+func failureType(
+	failures map[string]error,
+) *errpkg.Type {
+
+	errType := errors.ErrUnknownName
+
+	for _, err := range failures {
+		if t := failureOf(err); slices.Index(failurePriority, t) < slices.Index(failurePriority, errType) {
+			errType = t
+		}
+	}
+
+	return errType
+}
+
+func failureOf(
+	err error,
+) *errpkg.Type {
+
+	switch {
+	case errpkg.Has(err, errors.ErrCircuitOpen):
+		return errors.ErrCircuitOpen
+
+	case errpkg.Has(err, errors.ErrQuotaExhausted):
+		return errors.ErrQuotaExhausted
+
+	case errpkg.Has(err, errors.ErrRateLimited):
+		return errors.ErrRateLimited
+
+	case errpkg.Has(err, errors.ErrUnknownName),
+		errpkg.Has(err, errors.ErrLowConfidence):
+
+		return errors.ErrUnknownName
+
+	default:
+		return errors.ErrCantEnrichment
+	}
+}
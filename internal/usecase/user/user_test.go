@@ -0,0 +1,214 @@
+package user
+
+import (
+	"context"
+	stderrors "errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/internal/dto"
+	"github.com/jackvonhouse/enrichment/internal/errors"
+	errpkg "github.com/jackvonhouse/enrichment/pkg/errors"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+func TestFailureType(t *testing.T) {
+	var (
+		unknown = errors.ErrCantEnrichment.New("can't agify").
+			Wrap(errors.ErrUnknownName.New("unknown name"))
+		lowConfidence = errors.ErrLowConfidence.New("low confidence")
+		failed        = errors.ErrCantEnrichment.New("can't genderize")
+		rateLimited   = errors.ErrRateLimited.New("rate limited")
+		exhausted     = errors.ErrQuotaExhausted.New("quota exhausted")
+		circuitOpen   = errors.ErrCircuitOpen.New("circuit open")
+	)
+
+	tests := []struct {
+		name     string
+		failures map[string]error
+		want     *errpkg.Type
+	}{
+		{
+			name:     "unknown name",
+			failures: map[string]error{"age": unknown, "gender": lowConfidence},
+			want:     errors.ErrUnknownName,
+		},
+		{
+			name:     "failure outranks unknown name",
+			failures: map[string]error{"age": unknown, "gender": failed},
+			want:     errors.ErrCantEnrichment,
+		},
+		{
+			name:     "rate limit outranks failure",
+			failures: map[string]error{"age": failed, "gender": rateLimited},
+			want:     errors.ErrRateLimited,
+		},
+		{
+			name:     "exhausted quota outranks rate limit",
+			failures: map[string]error{"age": rateLimited, "country": exhausted},
+			want:     errors.ErrQuotaExhausted,
+		},
+		{
+			name: "open circuit outranks everything",
+			failures: map[string]error{
+				"age": exhausted, "gender": circuitOpen, "country": rateLimited,
+			},
+			want: errors.ErrCircuitOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Порядок обхода map случаен, поэтому проверяем несколько раз
+			for i := 0; i < 50; i++ {
+				if got := failureType(tt.failures); got != tt.want {
+					t.Fatalf("failureType() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// enrichmentStub отвечает только тем запросам, которые выполняются
+// одновременно: страна ждёт запроса возраста по подсказке, а запросы
+// возраста по определённым странам ждут друг друга
+type enrichmentStub struct {
+	countries map[string]string
+
+	hinted   chan struct{}
+	detected *sync.WaitGroup
+
+	mu     sync.Mutex
+	agify  map[string][]string
+	hintID string
+}
+
+func (e *enrichmentStub) AgifyBatch(
+	ctx context.Context,
+	names []string,
+	countryID string,
+) (map[string]*dto.AgeDTO, error) {
+
+	e.mu.Lock()
+	e.agify[countryID] = append([]string{}, names...)
+	e.mu.Unlock()
+
+	if countryID == e.hintID {
+		close(e.hinted)
+	} else {
+		e.detected.Done()
+
+		done := make(chan struct{})
+
+		go func() {
+			e.detected.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			return nil, stderrors.New("groups are enriched sequentially")
+		}
+	}
+
+	result := make(map[string]*dto.AgeDTO, len(names))
+	for _, name := range names {
+		result[name] = &dto.AgeDTO{Age: 30}
+	}
+
+	return result, nil
+}
+
+func (e *enrichmentStub) GenderizeBatch(
+	_ context.Context,
+	names []string,
+	_ string,
+) (map[string]*dto.GenderDTO, error) {
+
+	result := make(map[string]*dto.GenderDTO, len(names))
+	for _, name := range names {
+		result[name] = &dto.GenderDTO{Gender: "male"}
+	}
+
+	return result, nil
+}
+
+func (e *enrichmentStub) NationalizeBatch(
+	_ context.Context,
+	names []string,
+) (map[string]*dto.CountryDTO, error) {
+
+	select {
+	case <-e.hinted:
+	case <-time.After(time.Second):
+		return nil, stderrors.New("hinted group waits for nationalize")
+	}
+
+	result := make(map[string]*dto.CountryDTO, len(names))
+	for _, name := range names {
+		result[name] = &dto.CountryDTO{Country: e.countries[name]}
+	}
+
+	return result, nil
+}
+
+func (e *enrichmentStub) InferGender(string) (*dto.GenderDTO, bool) {
+	return nil, false
+}
+
+func (e *enrichmentStub) BypassCache(ctx context.Context) context.Context {
+	return ctx
+}
+
+func TestEnrichBatchRunsGroupsConcurrently(t *testing.T) {
+	hint := "KZ"
+
+	users := []dto.User{
+		{ID: 1, Name: "Dmitriy", CountryHint: &hint},
+		{ID: 2, Name: "Ivan"},
+		{ID: 3, Name: "Olena"},
+		{ID: 4, Name: "Petr"},
+	}
+
+	detected := &sync.WaitGroup{}
+	detected.Add(2)
+
+	enrichment := &enrichmentStub{
+		countries: map[string]string{"Dmitriy": "RU", "Ivan": "RU", "Olena": "UA", "Petr": "RU"},
+		hinted:    make(chan struct{}),
+		detected:  detected,
+		agify:     map[string][]string{},
+		hintID:    hint,
+	}
+
+	u := New(enrichment, nil, config.EnrichmentWorker{}, log.NewLogrusLogger())
+
+	enrichments, failures := u.enrichBatch(context.Background(), users)
+
+	if len(failures) > 0 {
+		t.Fatalf("failures = %v, want none", failures)
+	}
+
+	if len(enrichments) != len(users) {
+		t.Errorf("enriched %d users, want %d", len(enrichments), len(users))
+	}
+
+	for _, names := range enrichment.agify {
+		sort.Strings(names)
+	}
+
+	want := map[string][]string{
+		"KZ": {"Dmitriy"},
+		"RU": {"Ivan", "Petr"},
+		"UA": {"Olena"},
+	}
+
+	if !reflect.DeepEqual(enrichment.agify, want) {
+		t.Errorf("agify groups = %v, want %v", enrichment.agify, want)
+	}
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+type repositoryCache interface {
+	DeleteExpired(context.Context) (int64, error)
+}
+
+// Cleaner раз в interval удаляет истёкшие записи кеша,
+// которые иначе остались бы в таблице навсегда
+type Cleaner struct {
+	repository repositoryCache
+	interval   time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     *sync.WaitGroup
+
+	logger log.Logger
+}
+
+func New(
+	repository repositoryCache,
+	interval time.Duration,
+	logger log.Logger,
+) *Cleaner {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Cleaner{
+		repository: repository,
+		interval:   interval,
+		ctx:        ctx,
+		cancel:     cancel,
+		wg:         &sync.WaitGroup{},
+		logger:     logger.WithField("unit", "cache_cleaner"),
+	}
+}
+
+func (c *Cleaner) Run() {
+	c.wg.Add(1)
+
+	go c.work()
+}
+
+func (c *Cleaner) Shutdown(
+	ctx context.Context,
+) error {
+
+	c.cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Cleaner) work() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+
+		case <-ticker.C:
+		}
+
+		deleted, err := c.repository.DeleteExpired(c.ctx)
+		if err != nil {
+			c.logger.Warnf("can't delete expired cache entries: %s", err)
+
+			continue
+		}
+
+		c.logger.Infof("deleted %d expired cache entries", deleted)
+	}
+}
@@ -0,0 +1,118 @@
+package enrichment
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackvonhouse/enrichment/config"
+	"github.com/jackvonhouse/enrichment/pkg/log"
+)
+
+type useCaseUser interface {
+	EnrichPending(context.Context, int) (int, error)
+}
+
+type Pool struct {
+	useCase useCaseUser
+	config  config.EnrichmentWorker
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	wg     *sync.WaitGroup
+
+	logger log.Logger
+}
+
+func New(
+	useCase useCaseUser,
+	config config.EnrichmentWorker,
+	logger log.Logger,
+) *Pool {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Pool{
+		useCase: useCase,
+		config:  config,
+		ctx:     ctx,
+		cancel:  cancel,
+		stop:    make(chan struct{}),
+		wg:      &sync.WaitGroup{},
+		logger:  logger.WithField("unit", "worker"),
+	}
+}
+
+func (p *Pool) Run() {
+	for i := 0; i < p.config.Workers; i++ {
+		p.wg.Add(1)
+
+		go p.work(p.logger.WithField("worker", i))
+	}
+}
+
+// Shutdown дожидается завершения текущих порций, а по истечении
+// ctx прерывает их; незавершённые строки вернутся в очередь по аренде
+func (p *Pool) Shutdown(
+	ctx context.Context,
+) error {
+
+	close(p.stop)
+
+	done := make(chan struct{})
+
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+
+		return nil
+
+	case <-ctx.Done():
+		p.cancel()
+
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) work(
+	logger log.Logger,
+) {
+
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+
+		// Порция должна завершиться до истечения аренды, иначе её
+		// пользователей заберёт другой обработчик; десятая часть
+		// аренды остаётся на запись результатов
+		ctx, cancel := context.WithTimeout(p.ctx, p.config.Lease*9/10)
+		n, err := p.useCase.EnrichPending(ctx, p.config.BatchSize)
+		cancel()
+		if err != nil {
+			logger.Warnf("can't enrich pending users: %s", err)
+		}
+
+		// Пока очередь не пуста, забираем следующую порцию без ожидания
+		if err == nil && n > 0 {
+			continue
+		}
+
+		select {
+		case <-p.stop:
+			return
+
+		case <-time.After(p.config.PollInterval):
+		}
+	}
+}
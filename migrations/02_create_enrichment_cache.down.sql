@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS "enrichment_cache";
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,12 @@
+BEGIN;
+
+DROP TABLE IF EXISTS "enrichment_cache";
+CREATE TABLE "enrichment_cache" (
+    "key" TEXT PRIMARY KEY,
+    "value" JSONB NOT NULL,
+    "expires_at" TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX "enrichment_cache_expires_at_idx" ON "enrichment_cache" ("expires_at");
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE "users"
+    DROP COLUMN IF EXISTS "age_count",
+    DROP COLUMN IF EXISTS "gender_probability",
+    DROP COLUMN IF EXISTS "gender_count",
+    DROP COLUMN IF EXISTS "country_probability",
+    DROP COLUMN IF EXISTS "country_count",
+    DROP COLUMN IF EXISTS "countries";
+
+COMMIT;
\ No newline at end of file
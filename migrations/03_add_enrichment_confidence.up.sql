@@ -0,0 +1,11 @@
+BEGIN;
+
+ALTER TABLE "users"
+    ADD COLUMN "age_count" INTEGER,
+    ADD COLUMN "gender_probability" DOUBLE PRECISION,
+    ADD COLUMN "gender_count" INTEGER,
+    ADD COLUMN "country_probability" DOUBLE PRECISION,
+    ADD COLUMN "country_count" INTEGER,
+    ADD COLUMN "countries" JSONB;
+
+COMMIT;
\ No newline at end of file
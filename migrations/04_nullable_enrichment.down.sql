@@ -0,0 +1,12 @@
+BEGIN;
+
+UPDATE "users" SET "age" = 0 WHERE "age" IS NULL;
+UPDATE "users" SET "gender" = '' WHERE "gender" IS NULL;
+UPDATE "users" SET "country" = '' WHERE "country" IS NULL;
+
+ALTER TABLE "users"
+    ALTER COLUMN "age" SET NOT NULL,
+    ALTER COLUMN "gender" SET NOT NULL,
+    ALTER COLUMN "country" SET NOT NULL;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE "users"
+    ALTER COLUMN "age" DROP NOT NULL,
+    ALTER COLUMN "gender" DROP NOT NULL,
+    ALTER COLUMN "country" DROP NOT NULL;
+
+COMMIT;
\ No newline at end of file
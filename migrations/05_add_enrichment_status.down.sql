@@ -0,0 +1,12 @@
+BEGIN;
+
+DROP INDEX IF EXISTS "users_enrichment_queue_idx";
+
+ALTER TABLE "users"
+    DROP COLUMN IF EXISTS "enrichment_status",
+    DROP COLUMN IF EXISTS "enrichment_attempts",
+    DROP COLUMN IF EXISTS "enrichment_next_attempt_at",
+    DROP COLUMN IF EXISTS "enrichment_error",
+    DROP COLUMN IF EXISTS "enrichment_lease";
+
+COMMIT;
\ No newline at end of file
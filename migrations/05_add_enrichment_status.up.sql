@@ -0,0 +1,14 @@
+BEGIN;
+
+ALTER TABLE "users"
+    ADD COLUMN "enrichment_status" VARCHAR(16) NOT NULL DEFAULT 'done',
+    ADD COLUMN "enrichment_attempts" INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN "enrichment_next_attempt_at" TIMESTAMPTZ,
+    ADD COLUMN "enrichment_error" TEXT,
+    ADD COLUMN "enrichment_lease" INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX "users_enrichment_queue_idx"
+    ON "users" ("enrichment_next_attempt_at")
+    WHERE "enrichment_next_attempt_at" IS NOT NULL;
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE "users"
+    DROP COLUMN IF EXISTS "age_source",
+    DROP COLUMN IF EXISTS "gender_source",
+    DROP COLUMN IF EXISTS "country_source";
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE "users"
+    ADD COLUMN "age_source" VARCHAR(32),
+    ADD COLUMN "gender_source" VARCHAR(32),
+    ADD COLUMN "country_source" VARCHAR(32);
+
+COMMIT;
\ No newline at end of file
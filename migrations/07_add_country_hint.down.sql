@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE "users"
+    DROP COLUMN IF EXISTS "country_hint";
+
+COMMIT;
\ No newline at end of file
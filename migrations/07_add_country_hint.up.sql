@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE "users"
+    ADD COLUMN "country_hint" VARCHAR(2);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP INDEX IF EXISTS "users_patronymic_trgm_idx";
+DROP INDEX IF EXISTS "users_surname_trgm_idx";
+DROP INDEX IF EXISTS "users_name_trgm_idx";
+
+DROP FUNCTION IF EXISTS "immutable_unaccent"(TEXT);
+
+COMMIT;
\ No newline at end of file
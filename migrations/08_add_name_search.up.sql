@@ -0,0 +1,20 @@
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS "pg_trgm";
+CREATE EXTENSION IF NOT EXISTS "unaccent";
+
+-- unaccent() лишь STABLE и в индексе недопустима
+CREATE OR REPLACE FUNCTION "immutable_unaccent"(TEXT) RETURNS TEXT
+    LANGUAGE SQL IMMUTABLE PARALLEL SAFE STRICT
+    AS $$ SELECT public.unaccent('public.unaccent'::REGDICTIONARY, $1) $$;
+
+CREATE INDEX "users_name_trgm_idx"
+    ON "users" USING GIN (immutable_unaccent("name") gin_trgm_ops);
+
+CREATE INDEX "users_surname_trgm_idx"
+    ON "users" USING GIN (immutable_unaccent("surname") gin_trgm_ops);
+
+CREATE INDEX "users_patronymic_trgm_idx"
+    ON "users" USING GIN (immutable_unaccent("patronymic") gin_trgm_ops);
+
+COMMIT;
\ No newline at end of file
@@ -0,0 +1,23 @@
+package broker
+
+import "context"
+
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+}
+
+// Broker скрывает конкретную очередь сообщений, чтобы транспорт
+// можно было запускать поверх kafka или in-process заглушки
+type Broker interface {
+	Fetch(context.Context) (Message, error)
+	Commit(context.Context, Message) error
+
+	Publish(context.Context, string, Message) error
+
+	Close() error
+}
@@ -0,0 +1,183 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrClosed = errors.New("broker is closed")
+
+// Memory - in-process брокер: Fetch читает топик topic по порядку,
+// Publish дописывает сообщение в конец любого топика. Как и kafka,
+// Memory не выдаёт повторно прочитанное, но незакоммиченное сообщение
+type Memory struct {
+	topic string
+
+	mu        *sync.Mutex
+	topics    map[string][]Message
+	fetched   int
+	committed int64
+	closed    bool
+
+	// Закрывается и заменяется при каждом изменении
+	updated chan struct{}
+}
+
+func NewMemory(
+	topic string,
+) *Memory {
+
+	return &Memory{
+		topic:     topic,
+		mu:        &sync.Mutex{},
+		topics:    map[string][]Message{},
+		committed: -1,
+		updated:   make(chan struct{}),
+	}
+}
+
+func (m *Memory) Fetch(
+	ctx context.Context,
+) (Message, error) {
+
+	for {
+		m.mu.Lock()
+
+		if m.closed {
+			m.mu.Unlock()
+
+			return Message{}, ErrClosed
+		}
+
+		if messages := m.topics[m.topic]; m.fetched < len(messages) {
+			msg := messages[m.fetched]
+			m.fetched++
+
+			m.mu.Unlock()
+
+			return msg, nil
+		}
+
+		updated := m.updated
+
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+
+		case <-updated:
+		}
+	}
+}
+
+// Commit, как и Kafka, не выполняется с отменённым контекстом
+func (m *Memory) Commit(
+	ctx context.Context,
+	msg Message,
+) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+
+	m.committed = max(m.committed, msg.Offset)
+	m.notify()
+
+	return nil
+}
+
+func (m *Memory) Publish(
+	_ context.Context,
+	topic string,
+	msg Message,
+) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+
+	msg.Topic = topic
+	msg.Offset = int64(len(m.topics[topic]))
+
+	m.topics[topic] = append(m.topics[topic], msg)
+	m.notify()
+
+	return nil
+}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.closed {
+		m.closed = true
+		m.notify()
+	}
+
+	return nil
+}
+
+// Messages возвращает копию сообщений топика
+func (m *Memory) Messages(
+	topic string,
+) []Message {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message{}, m.topics[topic]...)
+}
+
+// Committed возвращает смещение последнего закоммиченного
+// сообщения, -1 - коммитов ещё не было
+func (m *Memory) Committed() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.committed
+}
+
+// WaitCommitted ожидает коммита сообщения со смещением offset
+func (m *Memory) WaitCommitted(
+	ctx context.Context,
+	offset int64,
+) error {
+
+	for {
+		m.mu.Lock()
+
+		if m.committed >= offset {
+			m.mu.Unlock()
+
+			return nil
+		}
+
+		updated := m.updated
+
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-updated:
+		}
+	}
+}
+
+func (m *Memory) notify() {
+	close(m.updated)
+	m.updated = make(chan struct{})
+}
@@ -8,6 +8,10 @@ func Is(err, target error) bool {
 	return errors.Is(err, target)
 }
 
+func As(err error, target any) bool {
+	return errors.As(err, target)
+}
+
 func Wrap(err error, wrapper *Instance) *Instance {
 	wrapper.Err = err
 